@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/Mr-Rafael/chirpy/internal/mailer"
+	"github.com/google/uuid"
+)
+
+// defaultAccountDeletionGrace is how long an account waits for deletion after
+// the user asks for it. Logging in during that time keeps the account.
+const defaultAccountDeletionGrace = 30 * 24 * time.Hour
+
+type accountDeletionRequestParams struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type accountDeletionResponseParams struct {
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+// handlerUsersDELETE schedules the caller's account for deletion. The user
+// has to confirm their password, and their second factor if they have one,
+// so a leaked access token alone can't get an account deleted.
+func (c *apiConfig) handlerUsersDELETE(writer http.ResponseWriter, request *http.Request) {
+	decoder := json.NewDecoder(request.Body)
+	reqParams := accountDeletionRequestParams{}
+	err := decoder.Decode(&reqParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to decode the request: %v", err), "Something went wrong", http.StatusBadRequest)
+		return
+	}
+	if len(reqParams.Password) <= 0 {
+		respondWithError(writer, "The password came empty.", "Missing param: password", http.StatusBadRequest)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userData, err := c.db.GetUserByID(context.Background(), jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get user data: %v", err), "User not found", http.StatusNotFound)
+		return
+	}
+	if !c.reauthenticate(writer, request, userData, reqParams.Password) {
+		return
+	}
+
+	totpData, err := c.db.GetUserTOTP(context.Background(), jwt_user_id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(writer, fmt.Sprintf("Failed to get the user's TOTP settings: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if err == nil && totpData.ConfirmedAt.Valid {
+		verified, err := c.verifySecondFactor(totpData, reqParams.Code)
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("Failed to verify the MFA code: %v", err), "Something went wrong.", http.StatusInternalServerError)
+			return
+		}
+		if !verified {
+			respondWithError(writer, "Account deletion with an invalid MFA code.", "Invalid code", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	deleteAfter, err := c.scheduleAccountDeletion(jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to schedule the account deletion: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	c.logSecurityEvent(jwt_user_id, "account_deletion_requested", fmt.Sprintf("The account will be deleted after %v; every refresh token and personal access token was revoked.", deleteAfter))
+
+	err = c.mailer.Send(mailer.Message{
+		To:      userData.Email,
+		Subject: "Your Chirpy account will be deleted",
+		Body: fmt.Sprintf("Your Chirpy account and everything you posted will be deleted after %v.\n\n"+
+			"If you change your mind, log in before then and the deletion will be cancelled.\n", deleteAfter.Format(time.RFC1123)),
+	})
+	if err != nil {
+		fmt.Printf("[Error]: Failed to send the account deletion email: %v\n", err)
+	}
+
+	respondWithJSON(writer, accountDeletionResponseParams{DeleteAfter: deleteAfter}, http.StatusAccepted)
+}
+
+// scheduleAccountDeletion marks the account for deletion and signs it out
+// everywhere, so logging in again is the only way back in. Asking again while
+// a deletion is pending keeps the original date.
+func (c *apiConfig) scheduleAccountDeletion(userID uuid.UUID) (time.Time, error) {
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	userData, err := qtx.ScheduleUserDeletion(context.Background(), database.ScheduleUserDeletionParams{
+		ID:          userID,
+		DeleteAfter: sql.NullTime{Time: time.Now().Add(c.accountDeletionGrace), Valid: true},
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to mark the account for deletion: %v", err)
+	}
+	err = qtx.RevokeAllUserRefreshTokens(context.Background(), userID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to revoke the refresh tokens: %v", err)
+	}
+	err = qtx.RevokeAllUserPersonalAccessTokens(context.Background(), userID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to revoke the personal access tokens: %v", err)
+	}
+	return userData.DeleteAfter.Time, tx.Commit()
+}
+
+// cancelAccountDeletion keeps an account that was waiting to be deleted. It's
+// called whenever the user logs in.
+func (c *apiConfig) cancelAccountDeletion(userID uuid.UUID) error {
+	err := c.db.CancelUserDeletion(context.Background(), userID)
+	if err != nil {
+		return err
+	}
+	c.logSecurityEvent(userID, "account_deletion_cancelled", "The user logged in, so the account will be kept.")
+	return nil
+}
+
+// purgeDeletedAccounts hard-deletes accounts whose grace period is over, once
+// at startup and then every hour, so a process restarted more often than that
+// still gets to it. Their chirps, tokens and everything else owned by them go
+// with the user row through ON DELETE CASCADE.
+func (c *apiConfig) purgeDeletedAccounts() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		c.deleteAccountsPastGracePeriod()
+		<-ticker.C
+	}
+}
+
+func (c *apiConfig) deleteAccountsPastGracePeriod() {
+	deleted, err := c.db.DeleteUsersPastGracePeriod(context.Background(), sql.NullTime{Time: time.Now(), Valid: true})
+	if err != nil {
+		fmt.Printf("[Error]: Failed to delete accounts past their grace period: %v\n", err)
+		return
+	}
+	for _, userID := range deleted {
+		fmt.Printf("[Security]: account_deleted for user %v: The deletion grace period ended.\n", userID)
+	}
+}
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type validateResponseErrorParams struct {
@@ -31,3 +40,130 @@ func respondWithJSON(writer http.ResponseWriter, data any, statusCode int) {
 	writer.WriteHeader(statusCode)
 	json.NewEncoder(writer).Encode(data)
 }
+
+// handlerJWKS publishes the public halves of the JWT signing keys so other
+// services can verify Chirpy tokens.
+func (c *apiConfig) handlerJWKS(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(writer, c.jwtKeys.JWKS(), http.StatusOK)
+}
+
+const (
+	scopeChirpsRead  = "chirps:read"
+	scopeChirpsWrite = "chirps:write"
+)
+
+var validScopes = []string{scopeChirpsRead, scopeChirpsWrite}
+
+var errMissingScope = errors.New("token is missing the required scope")
+
+var errPendingDeletion = errors.New("account is scheduled for deletion")
+
+// patTouchInterval is how stale a personal access token's last use may get
+// before a request records it again, so busy tokens don't write on every call.
+const patTouchInterval = time.Minute
+
+// authenticate identifies the caller from a JWT or a personal access token.
+// JWTs come from a password login and can do anything the user can; personal
+// access tokens only what their scopes allow. An account waiting to be deleted
+// can still read, but access tokens issued before the deletion was asked for
+// stay valid for a while, so writes are refused until the user logs back in.
+func (c *apiConfig) authenticate(request *http.Request, scope string) (uuid.UUID, error) {
+	user_id, scopes, err := c.identify(request)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if scopes != nil && !slices.Contains(scopes, scope) {
+		return uuid.Nil, fmt.Errorf("%w: personal access token lacks %v", errMissingScope, scope)
+	}
+	if scope == scopeChirpsWrite {
+		userData, err := c.db.GetUserByID(context.Background(), user_id)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to get user data: %v", err)
+		}
+		if userData.DeleteAfter.Valid {
+			return uuid.Nil, fmt.Errorf("%w: deletion after %v", errPendingDeletion, userData.DeleteAfter.Time)
+		}
+	}
+	return user_id, nil
+}
+
+// identify resolves the bearer token to a user without checking scopes. The
+// scopes are nil for a JWT, which isn't limited by any.
+func (c *apiConfig) identify(request *http.Request) (uuid.UUID, []string, error) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("failed to get bearer from request: %v", err)
+	}
+
+	if !auth.IsPersonalAccessToken(bearerToken) {
+		jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+		if err != nil {
+			return uuid.Nil, nil, fmt.Errorf("error validating JWT: %v", err)
+		}
+		if jwt_user_id == uuid.Nil {
+			return uuid.Nil, nil, fmt.Errorf("JWT has no user id")
+		}
+		return jwt_user_id, nil, nil
+	}
+
+	tokenData, err := c.db.GetPersonalAccessToken(context.Background(), auth.HashToken(bearerToken))
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("personal access token not found: %v", err)
+	}
+	if tokenData.RevokedAt.Valid {
+		return uuid.Nil, nil, fmt.Errorf("personal access token %v was revoked on %v", tokenData.ID, tokenData.RevokedAt.Time)
+	}
+	if tokenData.ExpiresAt.Valid && tokenData.ExpiresAt.Time.Before(time.Now()) {
+		return uuid.Nil, nil, fmt.Errorf("personal access token %v expired on %v", tokenData.ID, tokenData.ExpiresAt.Time)
+	}
+	if !tokenData.LastUsedAt.Valid || time.Since(tokenData.LastUsedAt.Time) >= patTouchInterval {
+		err = c.db.TouchPersonalAccessToken(context.Background(), tokenData.ID)
+		if err != nil {
+			fmt.Printf("[Error]: Failed to update the last use of personal access token %v: %v\n", tokenData.ID, err)
+		}
+	}
+	// Keep a token without scopes apart from a JWT, which has nil scopes.
+	scopes := tokenData.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return tokenData.UserID, scopes, nil
+}
+
+func respondWithAuthError(writer http.ResponseWriter, err error) {
+	if errors.Is(err, errMissingScope) {
+		respondWithError(writer, err.Error(), "Forbidden", http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, errPendingDeletion) {
+		respondWithError(writer, err.Error(), "The account is scheduled for deletion; log in again to keep it", http.StatusForbidden)
+		return
+	}
+	respondWithError(writer, fmt.Sprintf("Failed to authenticate the request: %v", err), "Unauthorized", http.StatusUnauthorized)
+}
+
+// optionalUserID identifies the caller on endpoints that also serve anonymous
+// readers. A missing or invalid token just means an anonymous request, and so
+// does a personal access token without chirps:read.
+func (c *apiConfig) optionalUserID(request *http.Request) uuid.NullUUID {
+	user_id, err := c.authenticate(request, scopeChirpsRead)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: user_id, Valid: true}
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// clientIP returns the address the request came from, without the port.
+func clientIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
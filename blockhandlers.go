@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type blockResponseParams struct {
+	UserID    uuid.UUID `json:"user_id"`
+	BlockedAt string    `json:"blocked_at"`
+}
+
+type muteResponseParams struct {
+	UserID  uuid.UUID `json:"user_id"`
+	MutedAt string    `json:"muted_at"`
+}
+
+// handlerBlockPOST blocks a user. Neither of them sees the other's chirps
+// from then on, and any follow between them is removed.
+func (c *apiConfig) handlerBlockPOST(writer http.ResponseWriter, request *http.Request) {
+	blockedID, err := uuid.Parse(request.PathValue("user_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the user id: %v", err), "Invalid User ID", http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if blockedID == jwt_user_id {
+		respondWithError(writer, "A user tried to block themselves.", "You can't block yourself", http.StatusBadRequest)
+		return
+	}
+	_, err = c.db.GetUserByID(context.Background(), blockedID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get user data: %v", err), "User not found", http.StatusNotFound)
+		return
+	}
+
+	err = c.blockUser(jwt_user_id, blockedID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to save the block to the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// blockUser saves the block and removes follows in both directions, so the
+// blocked user drops out of the blocker's timeline and vice versa.
+func (c *apiConfig) blockUser(blockerID uuid.UUID, blockedID uuid.UUID) error {
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	err = qtx.BlockUser(context.Background(), database.BlockUserParams{
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to block the user: %v", err)
+	}
+	err = qtx.DeleteFollowsBetween(context.Background(), database.DeleteFollowsBetweenParams{
+		UserID:  blockerID,
+		OtherID: blockedID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove the follows: %v", err)
+	}
+	return tx.Commit()
+}
+
+func (c *apiConfig) handlerBlockDELETE(writer http.ResponseWriter, request *http.Request) {
+	blockedID, err := uuid.Parse(request.PathValue("user_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the user id: %v", err), "Invalid User ID", http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err = c.db.UnblockUser(context.Background(), database.UnblockUserParams{
+		BlockerID: jwt_user_id,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to delete the block from the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) handlerBlocksGET(writer http.ResponseWriter, request *http.Request) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	queryResult, err := c.db.GetBlockedUsers(context.Background(), jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting blocked users from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	responseData := []blockResponseParams{}
+	for _, block := range queryResult {
+		responseData = append(responseData, blockResponseParams{
+			UserID:    block.BlockedID,
+			BlockedAt: block.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	respondWithJSON(writer, responseData, http.StatusOK)
+}
+
+// handlerMutePOST mutes a user, which only keeps their chirps out of the
+// caller's timeline. The muted user isn't told and can still interact.
+func (c *apiConfig) handlerMutePOST(writer http.ResponseWriter, request *http.Request) {
+	mutedID, err := uuid.Parse(request.PathValue("user_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the user id: %v", err), "Invalid User ID", http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if mutedID == jwt_user_id {
+		respondWithError(writer, "A user tried to mute themselves.", "You can't mute yourself", http.StatusBadRequest)
+		return
+	}
+	_, err = c.db.GetUserByID(context.Background(), mutedID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get user data: %v", err), "User not found", http.StatusNotFound)
+		return
+	}
+
+	err = c.db.MuteUser(context.Background(), database.MuteUserParams{
+		MuterID: jwt_user_id,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to save the mute to the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) handlerMuteDELETE(writer http.ResponseWriter, request *http.Request) {
+	mutedID, err := uuid.Parse(request.PathValue("user_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the user id: %v", err), "Invalid User ID", http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err = c.db.UnmuteUser(context.Background(), database.UnmuteUserParams{
+		MuterID: jwt_user_id,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to delete the mute from the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) handlerMutesGET(writer http.ResponseWriter, request *http.Request) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	queryResult, err := c.db.GetMutedUsers(context.Background(), jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting muted users from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	responseData := []muteResponseParams{}
+	for _, mute := range queryResult {
+		responseData = append(responseData, muteResponseParams{
+			UserID:  mute.MutedID,
+			MutedAt: mute.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	respondWithJSON(writer, responseData, http.StatusOK)
+}
+
+// isBlockedBetween reports whether either user blocked the other. A viewer
+// that isn't logged in is never blocked.
+func (c *apiConfig) isBlockedBetween(viewerID uuid.NullUUID, otherID uuid.UUID) (bool, error) {
+	if !viewerID.Valid {
+		return false, nil
+	}
+	return c.db.IsBlockedBetween(context.Background(), database.IsBlockedBetweenParams{
+		UserID:  viewerID.UUID,
+		OtherID: otherID,
+	})
+}
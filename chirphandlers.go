@@ -2,27 +2,81 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"sort"
 	"strings"
+	"time"
 
-	"github.com/Mr-Rafael/chirpy/internal/auth"
 	"github.com/Mr-Rafael/chirpy/internal/database"
 	"github.com/google/uuid"
 )
 
+const maxChirpLength = 140
+
 type chirpParams struct {
-	Body string `json:"body"`
+	Body      string     `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	QuoteOf   *uuid.UUID `json:"quote_of"`
 }
 
 type chirpResponseOKParams struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID              uuid.UUID              `json:"id"`
+	CreatedAt       string                 `json:"created_at"`
+	UpdatedAt       string                 `json:"updated_at"`
+	Body            string                 `json:"body"`
+	UserID          uuid.UUID              `json:"user_id"`
+	InReplyTo       *uuid.UUID             `json:"in_reply_to"`
+	RechirpOf       *uuid.UUID             `json:"rechirp_of"`
+	QuoteOf         *uuid.UUID             `json:"quote_of"`
+	Original        *chirpResponseOKParams `json:"original,omitempty"`
+	OriginalDeleted bool                   `json:"original_deleted,omitempty"`
+	Hashtags        []string               `json:"hashtags"`
+	LikeCount       int64                  `json:"like_count"`
+	LikedByMe       bool                   `json:"liked_by_me"`
+}
+
+type chirpThreadResponseParams struct {
+	Chirp           chirpResponseOKParams   `json:"chirp"`
+	Ancestors       []chirpResponseOKParams `json:"ancestors"`
+	Replies         []chirpResponseOKParams `json:"replies"`
+	AncestorDeleted bool                    `json:"ancestor_deleted"`
+}
+
+// chirpRow is a chirp as the chirp queries select it: every column except
+// body_tsv, which only search needs. sqlc gives each query its own row type,
+// but they all have this shape, so they convert to it.
+type chirpRow = struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	RechirpOf uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+}
+
+// toChirpRows wraps a chirp list query, as in toChirpRows(c.db.GetChirps(ctx)).
+func toChirpRows[T ~chirpRow](rows []T, err error) ([]chirpRow, error) {
+	if err != nil {
+		return nil, err
+	}
+	chirps := make([]chirpRow, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, chirpRow(row))
+	}
+	return chirps, nil
+}
+
+type chirpRevisionResponseParams struct {
+	ID         uuid.UUID `json:"id"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	Body       string    `json:"body"`
+	CreatedAt  string    `json:"created_at"`
+	ReplacedAt string    `json:"replaced_at"`
 }
 
 func (c *apiConfig) handlerChirpsPOST(writer http.ResponseWriter, request *http.Request) {
@@ -34,81 +88,299 @@ func (c *apiConfig) handlerChirpsPOST(writer http.ResponseWriter, request *http.
 		return
 	}
 
-	bearerToken, err := auth.GetBearerToken(request.Header)
+	user_id, err := c.authenticate(request, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(writer, err)
+		return
+	}
+
+	isValid := len(reqParams.Body) <= maxChirpLength
+
+	if !isValid {
+		respondWithError(writer, "Error: chirp too long", "Chirp is too long", http.StatusBadRequest)
+		return
+	}
+
+	inReplyTo := uuid.NullUUID{}
+	if reqParams.InReplyTo != nil {
+		parentChirp, err := c.db.GetChirp(context.Background(), *reqParams.InReplyTo)
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("Error fetching the parent chirp from the database: %v", err), "Parent chirp not found", http.StatusNotFound)
+			return
+		}
+		blocked, err := c.isBlockedBetween(uuid.NullUUID{UUID: user_id, Valid: true}, parentChirp.UserID)
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("Error checking blocks: %v", err), "Something went wrong.", http.StatusInternalServerError)
+			return
+		}
+		if blocked {
+			respondWithError(writer, "A user tried to reply to a chirp hidden by a block.", "Parent chirp not found", http.StatusNotFound)
+			return
+		}
+		inReplyTo = uuid.NullUUID{UUID: *reqParams.InReplyTo, Valid: true}
+	}
+
+	quoteOf := uuid.NullUUID{}
+	if reqParams.QuoteOf != nil {
+		if len(strings.TrimSpace(reqParams.Body)) == 0 {
+			respondWithError(writer, "A quote chirp came with an empty body.", "Quote chirps need a body", http.StatusBadRequest)
+			return
+		}
+		quotedChirp, err := c.db.GetChirp(context.Background(), *reqParams.QuoteOf)
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("Error fetching the quoted chirp from the database: %v", err), "Quoted chirp not found", http.StatusNotFound)
+			return
+		}
+		blocked, err := c.isBlockedBetween(uuid.NullUUID{UUID: user_id, Valid: true}, quotedChirp.UserID)
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("Error checking blocks: %v", err), "Something went wrong.", http.StatusInternalServerError)
+			return
+		}
+		if blocked {
+			respondWithError(writer, "A user tried to quote a chirp hidden by a block.", "Quoted chirp not found", http.StatusNotFound)
+			return
+		}
+		quoteOf = uuid.NullUUID{UUID: quotedChirp.ID, Valid: true}
+		if quotedChirp.RechirpOf.Valid {
+			quoteOf = quotedChirp.RechirpOf
+		}
+	}
+
+	createChirpParams := database.CreateChirpParams{
+		Body:      sanitizeText(reqParams.Body),
+		UserID:    user_id,
+		InReplyTo: inReplyTo,
+		QuoteOf:   quoteOf,
+	}
+	queryResult, err := c.createChirp(createChirpParams)
 	if err != nil {
-		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		respondWithError(writer, fmt.Sprintf("Error saving chirp on the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
 		return
 	}
-	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.secret)
+
+	respBody, err := c.chirpResponses(uuid.NullUUID{UUID: user_id, Valid: true}, []chirpRow{queryResult})
 	if err != nil {
-		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		respondWithError(writer, fmt.Sprintf("Error building the chirp response: %v", err), "Something went wrong.", http.StatusInternalServerError)
 		return
 	}
-	if jwt_user_id == uuid.Nil {
-		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+	respondWithJSON(writer, respBody[0], http.StatusCreated)
+}
+
+func (c *apiConfig) handlerRechirpPOST(writer http.ResponseWriter, request *http.Request) {
+	chirpID, err := uuid.Parse(request.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the chirp id: %v", err), "Invalid Chirp ID", http.StatusNotFound)
 		return
 	}
 
-	isValid := len(reqParams.Body) <= 140
+	user_id, err := c.authenticate(request, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(writer, err)
+		return
+	}
 
-	if !isValid {
-		respondWithError(writer, "Error: chirp too long", "Chirp is too long", http.StatusBadRequest)
+	originalChirp, err := c.db.GetChirp(context.Background(), chirpID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error fetching the Chirp from the database: %v", err), "Chirp not found", http.StatusNotFound)
+		return
+	}
+	blocked, err := c.isBlockedBetween(uuid.NullUUID{UUID: user_id, Valid: true}, originalChirp.UserID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error checking blocks: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if blocked {
+		respondWithError(writer, "A user tried to rechirp a chirp hidden by a block.", "Chirp not found", http.StatusNotFound)
 		return
 	}
+	// Rechirping a rechirp shares the chirp it points at.
+	rechirpOf := uuid.NullUUID{UUID: originalChirp.ID, Valid: true}
+	if originalChirp.RechirpOf.Valid {
+		rechirpOf = originalChirp.RechirpOf
+	}
 
-	createChirpParams := database.CreateChirpParams{
-		Body:   sanitizeText(reqParams.Body),
-		UserID: jwt_user_id,
+	createRechirpParams := database.CreateRechirpParams{
+		UserID:    user_id,
+		RechirpOf: rechirpOf,
+	}
+	queryResult, err := c.db.CreateRechirp(context.Background(), createRechirpParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(writer, "The user already rechirped this chirp.", "Chirp already rechirped", http.StatusConflict)
+		return
 	}
-	queryResult, err := c.db.CreateChirp(context.Background(), createChirpParams)
 	if err != nil {
-		respondWithError(writer, fmt.Sprintf("Error saving chirp on the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		respondWithError(writer, fmt.Sprintf("Error saving rechirp on the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
 		return
 	}
 
-	respBody := chirpResponseOKParams{
-		ID:        queryResult.ID,
-		CreatedAt: queryResult.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: queryResult.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-		Body:      queryResult.Body,
-		UserID:    queryResult.UserID,
+	respBody, err := c.chirpResponses(uuid.NullUUID{UUID: user_id, Valid: true}, []chirpRow{queryResult})
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error building the chirp response: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
 	}
-	respondWithJSON(writer, respBody, http.StatusCreated)
+	respondWithJSON(writer, respBody[0], http.StatusCreated)
+}
+
+// chirpFilter narrows a chirp listing. ViewerID is whoever is looking, so
+// chirps between them and users they blocked, or who blocked them, are left
+// out; it's unset for logged out requests.
+type chirpFilter struct {
+	AuthorID  uuid.NullUUID
+	InReplyTo uuid.NullUUID
+	ViewerID  uuid.NullUUID
 }
 
 func (c *apiConfig) handlerChirpsGET(writer http.ResponseWriter, request *http.Request) {
 	authorID := request.URL.Query().Get("author_id")
-	sortOrder := request.URL.Query().Get("sort")
 
-	var queryResult []database.Chirp
+	pageParams, err := parsePageParams(request.URL.Query())
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the pagination params: %v", err), "Invalid pagination params", http.StatusBadRequest)
+		return
+	}
 
+	filter := chirpFilter{ViewerID: c.optionalUserID(request)}
 	authorUUID, err := uuid.Parse(authorID)
-	if err != nil || len(authorUUID) == 0 {
-		queryResult, err = c.db.GetChirps(context.Background())
-	} else {
-		queryResult, err = c.db.GetChirpsByUser(context.Background(), authorUUID)
+	if err == nil {
+		filter.AuthorID = uuid.NullUUID{UUID: authorUUID, Valid: true}
 	}
+
+	queryResult, err := c.listChirps(pageParams, filter)
 	if err != nil {
 		respondWithError(writer, fmt.Sprintf("Error getting chirps from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
 	}
+	c.respondWithChirpPage(writer, request, pageParams, queryResult)
+}
 
-	switch sortOrder {
-	case "asc":
-		sort.Slice(queryResult, func(i, j int) bool { return queryResult[i].CreatedAt.Before(queryResult[j].CreatedAt) })
-	case "desc":
-		sort.Slice(queryResult, func(i, j int) bool { return queryResult[i].CreatedAt.After(queryResult[j].CreatedAt) })
+func (c *apiConfig) listChirps(pageParams pageQuery, filter chirpFilter) ([]chirpRow, error) {
+	cursorCreatedAt, cursorID := pageParams.cursorArgs()
+	if pageParams.queryDesc() {
+		return toChirpRows(c.db.ListChirpsDesc(context.Background(), database.ListChirpsDescParams{
+			AuthorID:        filter.AuthorID,
+			InReplyTo:       filter.InReplyTo,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			ViewerID:        filter.ViewerID,
+			Limit:           pageParams.queryLimit(),
+		}))
 	}
+	return toChirpRows(c.db.ListChirpsAsc(context.Background(), database.ListChirpsAscParams{
+		AuthorID:        filter.AuthorID,
+		InReplyTo:       filter.InReplyTo,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		ViewerID:        filter.ViewerID,
+		Limit:           pageParams.queryLimit(),
+	}))
+}
 
-	var responseData []chirpResponseOKParams
-	for _, chirp := range queryResult {
-		chirpData := chirpResponseOKParams{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			UpdatedAt: chirp.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		}
-		responseData = append(responseData, chirpData)
+func (c *apiConfig) respondWithChirpPage(writer http.ResponseWriter, request *http.Request, pageParams pageQuery, chirps []chirpRow) {
+	chirps, nextCursor, prevCursor := paginateChirps(pageParams, chirps)
+
+	responseData, err := c.chirpResponses(c.optionalUserID(request), chirps)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error building the chirps response: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	setPageLinks(writer, request, pageParams, nextCursor, prevCursor)
+	respondWithJSON(writer, responseData, http.StatusOK)
+}
+
+func (c *apiConfig) handlerChirpsReplies(writer http.ResponseWriter, request *http.Request) {
+	chirpID, err := uuid.Parse(request.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the chirp id: %v", err), "Invalid Chirp ID", http.StatusNotFound)
+		return
+	}
+
+	pageParams, err := parsePageParams(request.URL.Query())
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the pagination params: %v", err), "Invalid pagination params", http.StatusBadRequest)
+		return
+	}
+
+	_, err = c.db.GetChirp(context.Background(), chirpID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting chirp from database: %v", err), "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	filter := chirpFilter{
+		InReplyTo: uuid.NullUUID{UUID: chirpID, Valid: true},
+		ViewerID:  c.optionalUserID(request),
+	}
+	queryResult, err := c.listChirps(pageParams, filter)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting replies from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	c.respondWithChirpPage(writer, request, pageParams, queryResult)
+}
+
+func (c *apiConfig) handlerChirpsThread(writer http.ResponseWriter, request *http.Request) {
+	chirpID, err := uuid.Parse(request.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the chirp id: %v", err), "Invalid Chirp ID", http.StatusNotFound)
+		return
+	}
+
+	chirpData, err := c.db.GetChirp(context.Background(), chirpID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting chirp from database: %v", err), "Chirp not found", http.StatusNotFound)
+		return
+	}
+	viewerID := c.optionalUserID(request)
+	blocked, err := c.isBlockedBetween(viewerID, chirpData.UserID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error checking blocks: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if blocked {
+		respondWithError(writer, "A user asked for the thread of a chirp hidden by a block.", "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	ancestors, err := toChirpRows(c.db.GetChirpAncestors(context.Background(), database.GetChirpAncestorsParams{
+		ID:       chirpID,
+		ViewerID: viewerID,
+	}))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting chirp ancestors from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	pageParams := pageQuery{Limit: maxPageLimit}
+	replies, err := c.listChirps(pageParams, chirpFilter{InReplyTo: uuid.NullUUID{UUID: chirpID, Valid: true}, ViewerID: viewerID})
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting replies from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	replies, nextCursor, _ := paginateChirps(pageParams, replies)
+	if nextCursor != nil {
+		repliesURL := fmt.Sprintf("/api/chirps/%v/replies?cursor=%v&limit=%v", chirpID, encodeCursor(*nextCursor), pageParams.Limit)
+		writer.Header().Set("Link", fmt.Sprintf("<%v>; rel=\"next\"", repliesURL))
+	}
+
+	// The chain stops early when an ancestor was deleted or is hidden by a
+	// block; the topmost chirp we found still points at it.
+	topmost := chirpData
+	if len(ancestors) > 0 {
+		topmost = ancestors[0]
+	}
+
+	threadChirps := append(append(ancestors, chirpData), replies...)
+	threadResponses, err := c.chirpResponses(viewerID, threadChirps)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error building the thread response: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	responseData := chirpThreadResponseParams{
+		Chirp:           threadResponses[len(ancestors)],
+		Ancestors:       threadResponses[:len(ancestors)],
+		Replies:         threadResponses[len(ancestors)+1:],
+		AncestorDeleted: topmost.InReplyTo.Valid,
 	}
 	respondWithJSON(writer, responseData, http.StatusOK)
 }
@@ -125,14 +397,150 @@ func (c *apiConfig) handlerChirpsGETID(writer http.ResponseWriter, request *http
 		respondWithError(writer, fmt.Sprintf("Error getting chirps from database: %v", err), "Chirp not found", http.StatusNotFound)
 		return
 	}
-	responseData := chirpResponseOKParams{
-		ID:        queryResult.ID,
-		CreatedAt: queryResult.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: queryResult.UpdatedAt.Format("2006-01-02T15:04:05Z"),
-		Body:      queryResult.Body,
-		UserID:    queryResult.UserID,
+	viewerID := c.optionalUserID(request)
+	blocked, err := c.isBlockedBetween(viewerID, queryResult.UserID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error checking blocks: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
 	}
-	respondWithJSON(writer, responseData, http.StatusOK)
+	if blocked {
+		respondWithError(writer, "A user asked for a chirp hidden by a block.", "Chirp not found", http.StatusNotFound)
+		return
+	}
+	responseData, err := c.chirpResponses(viewerID, []chirpRow{queryResult})
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error building the chirp response: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(writer, responseData[0], http.StatusOK)
+}
+
+func newChirpResponse(chirp chirpRow) chirpResponseOKParams {
+	response := chirpResponseOKParams{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt: chirp.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+		Hashtags:  []string{},
+	}
+	if chirp.InReplyTo.Valid {
+		response.InReplyTo = &chirp.InReplyTo.UUID
+	}
+	if chirp.RechirpOf.Valid {
+		response.RechirpOf = &chirp.RechirpOf.UUID
+	}
+	if chirp.QuoteOf.Valid {
+		response.QuoteOf = &chirp.QuoteOf.UUID
+	}
+	return response
+}
+
+// chirpResponses converts chirps for the API, filling in the engagement data
+// and embedded originals the chirps table doesn't carry. viewerID may be null
+// for anonymous readers. An original hidden from the viewer by a block shows
+// up as deleted.
+func (c *apiConfig) chirpResponses(viewerID uuid.NullUUID, chirps []chirpRow) ([]chirpResponseOKParams, error) {
+	responses := []chirpResponseOKParams{}
+	if len(chirps) == 0 {
+		return responses, nil
+	}
+
+	originalIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.RechirpOf.Valid {
+			originalIDs = append(originalIDs, chirp.RechirpOf.UUID)
+		}
+		if chirp.QuoteOf.Valid {
+			originalIDs = append(originalIDs, chirp.QuoteOf.UUID)
+		}
+	}
+	originals := []chirpRow{}
+	if len(originalIDs) > 0 {
+		queryResult, err := toChirpRows(c.db.GetChirpsByIDs(context.Background(), database.GetChirpsByIDsParams{
+			Ids:      originalIDs,
+			ViewerID: viewerID,
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get original chirps: %v", err)
+		}
+		originals = queryResult
+	}
+
+	allChirps := append(originals, chirps...)
+	statsByChirp, err := c.likeStats(viewerID, allChirps)
+	if err != nil {
+		return nil, err
+	}
+	hashtagsByChirp, err := c.hashtags(allChirps)
+	if err != nil {
+		return nil, err
+	}
+	originalsByID := map[uuid.UUID]chirpResponseOKParams{}
+	for _, original := range originals {
+		response := newChirpResponse(original)
+		response.LikeCount = statsByChirp[original.ID].LikeCount
+		response.LikedByMe = statsByChirp[original.ID].LikedByMe
+		response.Hashtags = append(response.Hashtags, hashtagsByChirp[original.ID]...)
+		originalsByID[original.ID] = response
+	}
+
+	for _, chirp := range chirps {
+		response := newChirpResponse(chirp)
+		response.LikeCount = statsByChirp[chirp.ID].LikeCount
+		response.LikedByMe = statsByChirp[chirp.ID].LikedByMe
+		response.Hashtags = append(response.Hashtags, hashtagsByChirp[chirp.ID]...)
+
+		originalID := chirp.RechirpOf
+		if chirp.QuoteOf.Valid {
+			originalID = chirp.QuoteOf
+		}
+		if originalID.Valid {
+			original, ok := originalsByID[originalID.UUID]
+			if ok {
+				response.Original = &original
+			} else {
+				response.OriginalDeleted = true
+			}
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+func (c *apiConfig) likeStats(viewerID uuid.NullUUID, chirps []chirpRow) (map[uuid.UUID]database.GetChirpLikeStatsRow, error) {
+	chirpIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+	likeStats, err := c.db.GetChirpLikeStats(context.Background(), database.GetChirpLikeStatsParams{
+		ViewerID: viewerID,
+		ChirpIds: chirpIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get like stats: %v", err)
+	}
+	statsByChirp := map[uuid.UUID]database.GetChirpLikeStatsRow{}
+	for _, stats := range likeStats {
+		statsByChirp[stats.ChirpID] = stats
+	}
+	return statsByChirp, nil
+}
+
+func (c *apiConfig) hashtags(chirps []chirpRow) (map[uuid.UUID][]string, error) {
+	chirpIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+	chirpHashtags, err := c.db.GetChirpHashtags(context.Background(), chirpIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hashtags: %v", err)
+	}
+	hashtagsByChirp := map[uuid.UUID][]string{}
+	for _, chirpHashtag := range chirpHashtags {
+		hashtagsByChirp[chirpHashtag.ChirpID] = append(hashtagsByChirp[chirpHashtag.ChirpID], chirpHashtag.Tag)
+	}
+	return hashtagsByChirp, nil
 }
 
 func sanitizeText(text string) string {
@@ -150,6 +558,70 @@ func sanitizeText(text string) string {
 	return strings.Join(splitText, " ")
 }
 
+// createChirp saves a chirp together with the hashtags and mentions found in
+// its body.
+func (c *apiConfig) createChirp(params database.CreateChirpParams) (chirpRow, error) {
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return chirpRow{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	chirp, err := qtx.CreateChirp(context.Background(), params)
+	if err != nil {
+		return chirpRow{}, fmt.Errorf("failed to create chirp: %v", err)
+	}
+	err = qtx.CreateChirpHashtags(context.Background(), database.CreateChirpHashtagsParams{
+		ChirpID: chirp.ID,
+		Tags:    extractHashtags(chirp.Body),
+	})
+	if err != nil {
+		return chirpRow{}, fmt.Errorf("failed to save hashtags: %v", err)
+	}
+	err = saveMentions(qtx, chirp)
+	if err != nil {
+		return chirpRow{}, err
+	}
+	return chirp, tx.Commit()
+}
+
+// updateChirp edits a chirp's body and replaces its hashtags and mentions to
+// match. Users mentioned before the edit aren't notified twice.
+func (c *apiConfig) updateChirp(params database.UpdateChirpParams) (chirpRow, error) {
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return chirpRow{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	chirp, err := qtx.UpdateChirp(context.Background(), params)
+	if err != nil {
+		return chirpRow{}, fmt.Errorf("failed to update chirp: %v", err)
+	}
+	err = qtx.DeleteChirpHashtags(context.Background(), chirp.ID)
+	if err != nil {
+		return chirpRow{}, fmt.Errorf("failed to clear hashtags: %v", err)
+	}
+	err = qtx.CreateChirpHashtags(context.Background(), database.CreateChirpHashtagsParams{
+		ChirpID: chirp.ID,
+		Tags:    extractHashtags(chirp.Body),
+	})
+	if err != nil {
+		return chirpRow{}, fmt.Errorf("failed to save hashtags: %v", err)
+	}
+	err = qtx.DeleteChirpMentions(context.Background(), chirp.ID)
+	if err != nil {
+		return chirpRow{}, fmt.Errorf("failed to clear mentions: %v", err)
+	}
+	err = saveMentions(qtx, chirp)
+	if err != nil {
+		return chirpRow{}, err
+	}
+	return chirp, tx.Commit()
+}
+
 func (c *apiConfig) handlerChirpsDELETE(writer http.ResponseWriter, request *http.Request) {
 	chirpID, err := uuid.Parse(request.PathValue("chirp_id"))
 	if err != nil {
@@ -157,35 +629,131 @@ func (c *apiConfig) handlerChirpsDELETE(writer http.ResponseWriter, request *htt
 		return
 	}
 
-	bearerToken, err := auth.GetBearerToken(request.Header)
+	user_id, err := c.authenticate(request, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(writer, err)
+		return
+	}
+
+	chirpData, err := c.db.GetChirp(context.Background(), chirpID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error fetching the Chirp from the database: %v", err), "Something went wrong.", http.StatusNotFound)
+		return
+	}
+	if chirpData.UserID != user_id {
+		respondWithError(writer, "The Chirp's User ID doesn't match the JWT User ID.", "Unauthorized.", http.StatusForbidden)
+		return
+	}
+
+	err = c.db.DeleteChirp(context.Background(), chirpID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error saving chirp on the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) handlerChirpsPUT(writer http.ResponseWriter, request *http.Request) {
+	chirpID, err := uuid.Parse(request.PathValue("chirp_id"))
 	if err != nil {
-		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		respondWithError(writer, fmt.Sprintf("Failed to parse the chirp id: %v", err), "Invalid Chirp ID", http.StatusNotFound)
 		return
 	}
-	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.secret)
+
+	decoder := json.NewDecoder(request.Body)
+	reqParams := chirpParams{}
+	err = decoder.Decode(&reqParams)
 	if err != nil {
-		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		respondWithError(writer, fmt.Sprintf("Failed to decode the request body: %v", err), "Something went wrong", http.StatusBadRequest)
 		return
 	}
-	if jwt_user_id == uuid.Nil {
-		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+
+	user_id, err := c.authenticate(request, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(writer, err)
+		return
+	}
+
+	isValid := len(reqParams.Body) <= maxChirpLength
+
+	if !isValid {
+		respondWithError(writer, "Error: chirp too long", "Chirp is too long", http.StatusBadRequest)
 		return
 	}
 
 	chirpData, err := c.db.GetChirp(context.Background(), chirpID)
 	if err != nil {
-		respondWithError(writer, fmt.Sprintf("Error fetching the Chirp from the database: %v", err), "Something went wrong.", http.StatusNotFound)
+		respondWithError(writer, fmt.Sprintf("Error fetching the Chirp from the database: %v", err), "Chirp not found", http.StatusNotFound)
 		return
 	}
-	if chirpData.UserID != jwt_user_id {
+	if chirpData.UserID != user_id {
 		respondWithError(writer, "The Chirp's User ID doesn't match the JWT User ID.", "Unauthorized.", http.StatusForbidden)
 		return
 	}
+	if chirpData.RechirpOf.Valid {
+		respondWithError(writer, "Attempted to edit a rechirp.", "Rechirps can't be edited", http.StatusBadRequest)
+		return
+	}
 
-	err = c.db.DeleteChirp(context.Background(), chirpID)
+	sanitizedBody := sanitizeText(reqParams.Body)
+	if sanitizedBody != chirpData.Body {
+		updateChirpParams := database.UpdateChirpParams{
+			ID:   chirpID,
+			Body: sanitizedBody,
+		}
+		chirpData, err = c.updateChirp(updateChirpParams)
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("Error updating the chirp on the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	respBody, err := c.chirpResponses(uuid.NullUUID{UUID: user_id, Valid: true}, []chirpRow{chirpData})
 	if err != nil {
-		respondWithError(writer, fmt.Sprintf("Error saving chirp on the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		respondWithError(writer, fmt.Sprintf("Error building the chirp response: %v", err), "Something went wrong.", http.StatusInternalServerError)
 		return
 	}
-	writer.WriteHeader(http.StatusNoContent)
+	respondWithJSON(writer, respBody[0], http.StatusOK)
+}
+
+func (c *apiConfig) handlerChirpsHistory(writer http.ResponseWriter, request *http.Request) {
+	chirpID, err := uuid.Parse(request.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the chirp id: %v", err), "Invalid Chirp ID", http.StatusNotFound)
+		return
+	}
+
+	chirpData, err := c.db.GetChirp(context.Background(), chirpID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting chirp from database: %v", err), "Chirp not found", http.StatusNotFound)
+		return
+	}
+	blocked, err := c.isBlockedBetween(c.optionalUserID(request), chirpData.UserID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error checking blocks: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if blocked {
+		respondWithError(writer, "A user asked for the history of a chirp hidden by a block.", "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	queryResult, err := c.db.GetChirpRevisions(context.Background(), chirpID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting chirp revisions from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	responseData := []chirpRevisionResponseParams{}
+	for _, revision := range queryResult {
+		revisionData := chirpRevisionResponseParams{
+			ID:         revision.ID,
+			ChirpID:    revision.ChirpID,
+			Body:       revision.Body,
+			CreatedAt:  revision.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			ReplacedAt: revision.ReplacedAt.Format("2006-01-02T15:04:05Z"),
+		}
+		responseData = append(responseData, revisionData)
+	}
+	respondWithJSON(writer, responseData, http.StatusOK)
 }
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxImportSize   = 1 << 20
+	maxImportChirps = 1000
+)
+
+// importRecord is one chirp read from an uploaded archive. Line is where it
+// started in the file, for the error report.
+type importRecord struct {
+	Line      int
+	Body      string
+	CreatedAt string
+}
+
+type chirpImportLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type chirpImportResponseParams struct {
+	Imported int                    `json:"imported"`
+	Errors   []chirpImportLineError `json:"errors"`
+}
+
+// handlerChirpsImport creates chirps from an uploaded JSON Lines or CSV file,
+// keeping their original timestamps. Each record has a body and an optional
+// RFC 3339 created_at; CSV files need a header row naming those columns.
+// Records that fail validation are skipped and reported by line, and the
+// rest are imported.
+func (c *apiConfig) handlerChirpsImport(writer http.ResponseWriter, request *http.Request) {
+	user_id, err := c.authenticate(request, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(writer, err)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Invalid import content type: %v", err), "Upload a JSON Lines or CSV file", http.StatusUnsupportedMediaType)
+		return
+	}
+	body := http.MaxBytesReader(writer, request.Body, maxImportSize)
+	var records []importRecord
+	var lineErrors []chirpImportLineError
+	switch mediaType {
+	case "application/jsonl", "application/x-ndjson":
+		records, lineErrors, err = readJSONLinesImport(body)
+	case "text/csv":
+		records, lineErrors, err = readCSVImport(body)
+	default:
+		respondWithError(writer, fmt.Sprintf("Unsupported import content type: %v", mediaType), "Upload a JSON Lines or CSV file", http.StatusUnsupportedMediaType)
+		return
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondWithError(writer, fmt.Sprintf("Import file too large: %v", err), "The file is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to read the import file: %v", err), "The file couldn't be read", http.StatusBadRequest)
+		return
+	}
+	if len(records) > maxImportChirps {
+		respondWithError(writer, fmt.Sprintf("Import with %v chirps", len(records)), fmt.Sprintf("Can't import more than %v chirps at once", maxImportChirps), http.StatusBadRequest)
+		return
+	}
+
+	importParams, lineErrors := validateImportRecords(user_id, records, lineErrors, time.Now())
+	err = c.importChirps(importParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error saving imported chirps on the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	responseBody := chirpImportResponseParams{
+		Imported: len(importParams),
+		Errors:   lineErrors,
+	}
+	respondWithJSON(writer, responseBody, http.StatusOK)
+}
+
+// validateImportRecords turns the records that pass validation into chirps for
+// userID. The ones that don't are added to lineErrors, which comes back sorted
+// by line.
+func validateImportRecords(userID uuid.UUID, records []importRecord, lineErrors []chirpImportLineError, now time.Time) ([]database.ImportChirpParams, []chirpImportLineError) {
+	importParams := []database.ImportChirpParams{}
+	for _, record := range records {
+		params, err := validateImportRecord(record, now)
+		if err != nil {
+			lineErrors = append(lineErrors, chirpImportLineError{Line: record.Line, Error: err.Error()})
+			continue
+		}
+		params.UserID = userID
+		importParams = append(importParams, params)
+	}
+	sort.SliceStable(lineErrors, func(i, j int) bool {
+		return lineErrors[i].Line < lineErrors[j].Line
+	})
+	return importParams, lineErrors
+}
+
+// validateImportRecord applies the same rules as posting a chirp, plus a
+// sanity check on the timestamp.
+func validateImportRecord(record importRecord, now time.Time) (database.ImportChirpParams, error) {
+	if len(strings.TrimSpace(record.Body)) == 0 {
+		return database.ImportChirpParams{}, errors.New("body is empty")
+	}
+	if len(record.Body) > maxChirpLength {
+		return database.ImportChirpParams{}, errors.New("chirp is too long")
+	}
+	createdAt := now
+	if record.CreatedAt != "" {
+		parsed, err := time.Parse(time.RFC3339, record.CreatedAt)
+		if err != nil {
+			return database.ImportChirpParams{}, fmt.Errorf("created_at isn't an RFC 3339 timestamp: %v", record.CreatedAt)
+		}
+		if parsed.After(now) {
+			return database.ImportChirpParams{}, errors.New("created_at is in the future")
+		}
+		createdAt = parsed
+	}
+	return database.ImportChirpParams{
+		CreatedAt: createdAt.UTC(),
+		Body:      sanitizeText(record.Body),
+	}, nil
+}
+
+func readJSONLinesImport(body io.Reader) ([]importRecord, []chirpImportLineError, error) {
+	records := []importRecord{}
+	lineErrors := []chirpImportLineError{}
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var fields struct {
+			Body      string `json:"body"`
+			CreatedAt string `json:"created_at"`
+		}
+		err := json.Unmarshal(scanner.Bytes(), &fields)
+		if err != nil {
+			lineErrors = append(lineErrors, chirpImportLineError{Line: line, Error: fmt.Sprintf("invalid JSON: %v", err)})
+			continue
+		}
+		records = append(records, importRecord{Line: line, Body: fields.Body, CreatedAt: fields.CreatedAt})
+	}
+	return records, lineErrors, scanner.Err()
+}
+
+func readCSVImport(body io.Reader) ([]importRecord, []chirpImportLineError, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the header row: %v", err)
+	}
+	bodyColumn, createdAtColumn := -1, -1
+	for i, name := range header {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "body":
+			bodyColumn = i
+		case "created_at":
+			createdAtColumn = i
+		}
+	}
+	if bodyColumn < 0 {
+		return nil, nil, errors.New("the header row has no body column")
+	}
+
+	records := []importRecord{}
+	lineErrors := []chirpImportLineError{}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			lineErrors = append(lineErrors, chirpImportLineError{Line: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		record := importRecord{Line: line}
+		if bodyColumn < len(row) {
+			record.Body = row[bodyColumn]
+		}
+		if createdAtColumn >= 0 && createdAtColumn < len(row) {
+			record.CreatedAt = strings.TrimSpace(row[createdAtColumn])
+		}
+		records = append(records, record)
+	}
+	return records, lineErrors, nil
+}
+
+// importChirps saves imported chirps with their hashtags and mentions. Unlike
+// createChirp it doesn't notify mentioned users, since the chirps are old and
+// a big import would flood their notifications.
+func (c *apiConfig) importChirps(chirps []database.ImportChirpParams) error {
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	for _, params := range chirps {
+		chirp, err := qtx.ImportChirp(context.Background(), params)
+		if err != nil {
+			return fmt.Errorf("failed to import chirp: %v", err)
+		}
+		err = qtx.CreateChirpHashtags(context.Background(), database.CreateChirpHashtagsParams{
+			ChirpID: chirp.ID,
+			Tags:    extractHashtags(chirp.Body),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save hashtags: %v", err)
+		}
+		err = qtx.CreateChirpMentions(context.Background(), database.CreateChirpMentionsParams{
+			ChirpID:   chirp.ID,
+			Usernames: extractMentions(chirp.Body),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save mentions: %v", err)
+		}
+	}
+	return tx.Commit()
+}
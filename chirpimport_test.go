@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateImportRecord(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		record        importRecord
+		wantErr       string
+		wantBody      string
+		wantCreatedAt time.Time
+	}{
+		{
+			name:          "no timestamp uses now",
+			record:        importRecord{Body: "hello"},
+			wantBody:      "hello",
+			wantCreatedAt: now,
+		},
+		{
+			name:          "timestamp with an offset is stored in UTC",
+			record:        importRecord{Body: "hello", CreatedAt: "2024-03-01T10:00:00+02:00"},
+			wantBody:      "hello",
+			wantCreatedAt: time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "timestamp equal to now",
+			record:        importRecord{Body: "hello", CreatedAt: "2025-06-01T12:00:00Z"},
+			wantBody:      "hello",
+			wantCreatedAt: now,
+		},
+		{
+			name:          "profanity is masked",
+			record:        importRecord{Body: "what a kerfuffle"},
+			wantBody:      "what a ****",
+			wantCreatedAt: now,
+		},
+		{
+			name:          "longest allowed body",
+			record:        importRecord{Body: strings.Repeat("a", maxChirpLength)},
+			wantBody:      strings.Repeat("a", maxChirpLength),
+			wantCreatedAt: now,
+		},
+		{
+			name:    "empty body",
+			record:  importRecord{Body: ""},
+			wantErr: "body is empty",
+		},
+		{
+			name:    "whitespace body",
+			record:  importRecord{Body: " \t\n"},
+			wantErr: "body is empty",
+		},
+		{
+			name:    "body too long",
+			record:  importRecord{Body: strings.Repeat("a", maxChirpLength+1)},
+			wantErr: "chirp is too long",
+		},
+		{
+			name:    "future timestamp",
+			record:  importRecord{Body: "hello", CreatedAt: "2025-06-01T12:00:01Z"},
+			wantErr: "created_at is in the future",
+		},
+		{
+			name:    "date without a time",
+			record:  importRecord{Body: "hello", CreatedAt: "2024-03-01"},
+			wantErr: "created_at isn't an RFC 3339 timestamp: 2024-03-01",
+		},
+		{
+			name:    "not a timestamp",
+			record:  importRecord{Body: "hello", CreatedAt: "yesterday"},
+			wantErr: "created_at isn't an RFC 3339 timestamp: yesterday",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, err := validateImportRecord(test.record, now)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("Expected error %q, got %v.", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if params.Body != test.wantBody {
+				t.Errorf("Expected body %q, got %q.", test.wantBody, params.Body)
+			}
+			if !params.CreatedAt.Equal(test.wantCreatedAt) || params.CreatedAt.Location() != time.UTC {
+				t.Errorf("Expected created_at %v, got %v.", test.wantCreatedAt, params.CreatedAt)
+			}
+		})
+	}
+}
+
+func TestReadCSVImport(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		wantErr        bool
+		wantRecords    []importRecord
+		wantLineErrors []chirpImportLineError
+	}{
+		{
+			name:  "header in any order and case",
+			input: "Created_At, BODY\n2024-03-01T10:00:00Z,hello\n,world\n",
+			wantRecords: []importRecord{
+				{Line: 2, Body: "hello", CreatedAt: "2024-03-01T10:00:00Z"},
+				{Line: 3, Body: "world"},
+			},
+		},
+		{
+			name:  "extra columns and short rows",
+			input: "id,body,created_at\n1,hello\n2,world, 2024-03-01T10:00:00Z \n",
+			wantRecords: []importRecord{
+				{Line: 2, Body: "hello"},
+				{Line: 3, Body: "world", CreatedAt: "2024-03-01T10:00:00Z"},
+			},
+		},
+		{
+			name:  "quoted newlines and blank lines keep file line numbers",
+			input: "body\n\"first\nsecond\"\n\nthird\n",
+			wantRecords: []importRecord{
+				{Line: 2, Body: "first\nsecond"},
+				{Line: 5, Body: "third"},
+			},
+		},
+		{
+			name:  "bad quoting is reported and reading goes on",
+			input: "body\nfine\nbroken \"quote\" here\nalso fine\n",
+			wantRecords: []importRecord{
+				{Line: 2, Body: "fine"},
+				{Line: 4, Body: "also fine"},
+			},
+			wantLineErrors: []chirpImportLineError{
+				{Line: 3, Error: csv.ErrBareQuote.Error()},
+			},
+		},
+		{
+			name:    "no body column",
+			input:   "text,created_at\nhello,2024-03-01T10:00:00Z\n",
+			wantErr: true,
+		},
+		{
+			name:    "first row is data, not a header",
+			input:   "hello\nworld\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty file",
+			input:   "",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			records, line_errors, err := readCSVImport(strings.NewReader(test.input))
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %v records.", len(records))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			checkImportRead(t, records, line_errors, test.wantRecords, test.wantLineErrors)
+		})
+	}
+}
+
+func TestReadJSONLinesImport(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		wantErr        error
+		wantRecords    []importRecord
+		wantLineErrors []chirpImportLineError
+	}{
+		{
+			name:  "blank lines keep file line numbers",
+			input: "{\"body\":\"hello\",\"created_at\":\"2024-03-01T10:00:00Z\"}\n\n  \n{\"body\":\"world\"}",
+			wantRecords: []importRecord{
+				{Line: 1, Body: "hello", CreatedAt: "2024-03-01T10:00:00Z"},
+				{Line: 4, Body: "world"},
+			},
+		},
+		{
+			name:  "CRLF line endings",
+			input: "{\"body\":\"hello\"}\r\n{\"body\":\"world\"}\r\n",
+			wantRecords: []importRecord{
+				{Line: 1, Body: "hello"},
+				{Line: 2, Body: "world"},
+			},
+		},
+		{
+			name:  "invalid JSON is reported and reading goes on",
+			input: "{\"body\":\"hello\"}\nnot json\n{\"body\":\"world\"}\n",
+			wantRecords: []importRecord{
+				{Line: 1, Body: "hello"},
+				{Line: 3, Body: "world"},
+			},
+			wantLineErrors: []chirpImportLineError{
+				{Line: 2, Error: "invalid JSON: invalid character 'o' in literal null (expecting 'u')"},
+			},
+		},
+		{
+			name:  "line at the buffer cap",
+			input: "{\"body\":\"" + strings.Repeat("a", maxImportSize-12) + "\"}\n",
+			wantRecords: []importRecord{
+				{Line: 1, Body: strings.Repeat("a", maxImportSize-12)},
+			},
+		},
+		{
+			name:    "line over the buffer cap",
+			input:   "{\"body\":\"hello\"}\n{\"body\":\"" + strings.Repeat("a", maxImportSize) + "\"}\n",
+			wantErr: bufio.ErrTooLong,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			records, line_errors, err := readJSONLinesImport(strings.NewReader(test.input))
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("Expected error %v, got %v.", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			checkImportRead(t, records, line_errors, test.wantRecords, test.wantLineErrors)
+		})
+	}
+}
+
+func TestValidateImportRecords(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	user_id := uuid.New()
+	input := "body,created_at\n" +
+		"first,2024-03-01T10:00:00Z\n" +
+		"second,2030-01-01T00:00:00Z\n" +
+		"broken \"quote\",\n" +
+		",\n" +
+		"fifth,\n"
+
+	records, line_errors, err := readCSVImport(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to read the import: %v", err)
+	}
+	params, line_errors := validateImportRecords(user_id, records, line_errors, now)
+
+	if len(params) != 2 || params[0].Body != "first" || params[1].Body != "fifth" {
+		t.Fatalf("Expected the first and fifth chirps to be imported, got %+v.", params)
+	}
+	for _, chirp := range params {
+		if chirp.UserID != user_id {
+			t.Errorf("Expected the chirps to belong to %v, got %v.", user_id, chirp.UserID)
+		}
+	}
+	want_errors := []chirpImportLineError{
+		{Line: 3, Error: "created_at is in the future"},
+		{Line: 4, Error: csv.ErrBareQuote.Error()},
+		{Line: 5, Error: "body is empty"},
+	}
+	if !reflect.DeepEqual(line_errors, want_errors) {
+		t.Errorf("Expected line errors %+v, got %+v.", want_errors, line_errors)
+	}
+}
+
+func checkImportRead(t *testing.T, records []importRecord, lineErrors []chirpImportLineError, wantRecords []importRecord, wantLineErrors []chirpImportLineError) {
+	t.Helper()
+	if wantLineErrors == nil {
+		wantLineErrors = []chirpImportLineError{}
+	}
+	if !reflect.DeepEqual(records, wantRecords) {
+		t.Errorf("Expected records %+v, got %+v.", wantRecords, records)
+	}
+	if !reflect.DeepEqual(lineErrors, wantLineErrors) {
+		t.Errorf("Expected line errors %+v, got %+v.", wantLineErrors, lineErrors)
+	}
+}
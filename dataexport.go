@@ -0,0 +1,416 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// dataExportLifetime is how long an export can be downloaded after it was
+// requested. The archive is removed once it expires or has been downloaded.
+const dataExportLifetime = 24 * time.Hour
+
+// An export starts out "pending" and ends up in one of these.
+const (
+	dataExportReady  = "ready"
+	dataExportFailed = "failed"
+)
+
+type dataExportResponseParams struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	Downloaded  bool       `json:"downloaded"`
+}
+
+type exportedChirp struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	RechirpOf *uuid.UUID `json:"rechirp_of"`
+	QuoteOf   *uuid.UUID `json:"quote_of"`
+}
+
+type exportedLike struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportedFollow struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportedFollows struct {
+	Following []exportedFollow `json:"following"`
+	Followers []exportedFollow `json:"followers"`
+}
+
+// exportedSession is one refresh token of a session. The token hash is left
+// out; it's useless to the user and shouldn't leave the database.
+type exportedSession struct {
+	SessionID uuid.UUID  `json:"session_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	UserAgent string     `json:"user_agent"`
+	IPAddress string     `json:"ip_address"`
+}
+
+// handlerDataExportPOST starts building an archive of everything stored about
+// the caller. It answers right away; the client polls the export until it's
+// ready and then downloads it.
+func (c *apiConfig) handlerDataExportPOST(writer http.ResponseWriter, request *http.Request) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	exportData, err := c.db.CreateDataExport(context.Background(), database.CreateDataExportParams{
+		UserID:    jwt_user_id,
+		ExpiresAt: time.Now().Add(dataExportLifetime),
+	})
+	if isUniqueViolation(err) {
+		respondWithError(writer, fmt.Sprintf("A data export is already in progress: %v", err), "An export is already in progress", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to create the data export: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	go c.buildDataExport(exportData.ID, jwt_user_id)
+
+	writer.Header().Set("Location", "/api/users/me/export/"+exportData.ID.String())
+	respondWithJSON(writer, newDataExportResponse(exportData), http.StatusAccepted)
+}
+
+func (c *apiConfig) handlerDataExportGET(writer http.ResponseWriter, request *http.Request) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	exportID, err := uuid.Parse(request.PathValue("export_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Invalid export ID: %v", err), "Export not found", http.StatusNotFound)
+		return
+	}
+	exportData, err := c.db.GetDataExport(context.Background(), database.GetDataExportParams{
+		ID:     exportID,
+		UserID: jwt_user_id,
+	})
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get the data export: %v", err), "Export not found", http.StatusNotFound)
+		return
+	}
+	respondWithJSON(writer, newDataExportResponse(exportData), http.StatusOK)
+}
+
+// handlerDataExportDownload sends the archive and deletes it. Each export can
+// only be downloaded once, so a copy isn't left lying around on the server.
+func (c *apiConfig) handlerDataExportDownload(writer http.ResponseWriter, request *http.Request) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	exportID, err := uuid.Parse(request.PathValue("export_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Invalid export ID: %v", err), "Export not found", http.StatusNotFound)
+		return
+	}
+	exportData, err := c.db.GetDataExport(context.Background(), database.GetDataExportParams{
+		ID:     exportID,
+		UserID: jwt_user_id,
+	})
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get the data export: %v", err), "Export not found", http.StatusNotFound)
+		return
+	}
+	if exportData.Status != dataExportReady {
+		respondWithError(writer, fmt.Sprintf("Download of a data export with status %v", exportData.Status), "The export isn't ready", http.StatusConflict)
+		return
+	}
+
+	// Open the archive before claiming the download, so a missing file
+	// doesn't use up the only download.
+	archivePath := c.dataExportPath(exportID)
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to open the data export: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	defer archive.Close()
+
+	downloaded, err := c.db.MarkDataExportDownloaded(context.Background(), exportID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to mark the data export downloaded: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if downloaded == 0 {
+		respondWithError(writer, "The data export was already downloaded or expired.", "The export is no longer available", http.StatusGone)
+		return
+	}
+	defer os.Remove(archivePath)
+
+	writer.Header().Set("Content-Type", "application/zip")
+	writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chirpy-export-%v.zip"`, exportData.CreatedAt.Format("2006-01-02")))
+	writer.WriteHeader(http.StatusOK)
+	_, err = io.Copy(writer, archive)
+	if err != nil {
+		fmt.Printf("[Error]: Failed to send data export %v: %v\n", exportID, err)
+	}
+}
+
+func newDataExportResponse(exportData database.DataExport) dataExportResponseParams {
+	response := dataExportResponseParams{
+		ID:         exportData.ID,
+		Status:     exportData.Status,
+		CreatedAt:  exportData.CreatedAt,
+		ExpiresAt:  exportData.ExpiresAt,
+		Downloaded: exportData.DownloadedAt.Valid,
+	}
+	if exportData.CompletedAt.Valid {
+		response.CompletedAt = &exportData.CompletedAt.Time
+	}
+	return response
+}
+
+func (c *apiConfig) dataExportPath(exportID uuid.UUID) string {
+	return filepath.Join(c.exportDir, exportID.String()+".zip")
+}
+
+// buildDataExport writes the archive and records whether it worked. It runs
+// in the background, so errors only end up in the log and the export status.
+func (c *apiConfig) buildDataExport(exportID uuid.UUID, userID uuid.UUID) {
+	status := dataExportReady
+	err := c.writeDataExport(c.dataExportPath(exportID), userID)
+	if err != nil {
+		fmt.Printf("[Error]: Failed to build data export %v: %v\n", exportID, err)
+		status = dataExportFailed
+	}
+	err = c.db.FinishDataExport(context.Background(), database.FinishDataExportParams{
+		ID:     exportID,
+		Status: status,
+	})
+	if err != nil {
+		fmt.Printf("[Error]: Failed to update data export %v: %v\n", exportID, err)
+	}
+}
+
+// writeDataExport collects the user's data into a zip at archivePath. The
+// zip is written under a temporary name first, so a half written archive is
+// never served.
+func (c *apiConfig) writeDataExport(archivePath string, userID uuid.UUID) error {
+	userData, err := c.db.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to get the user: %v", err)
+	}
+	chirps, err := c.db.GetChirpsByUser(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to get the chirps: %v", err)
+	}
+	likes, err := c.db.GetLikesByUser(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to get the likes: %v", err)
+	}
+	following, err := c.db.GetFollowing(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to get the followed users: %v", err)
+	}
+	followers, err := c.db.GetFollowers(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to get the followers: %v", err)
+	}
+	refreshTokens, err := c.db.GetRefreshTokensByUser(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to get the sessions: %v", err)
+	}
+
+	exportedChirps := make([]exportedChirp, 0, len(chirps))
+	for _, chirp := range chirps {
+		exported := exportedChirp{
+			ID:        chirp.ID,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Body:      chirp.Body,
+		}
+		if chirp.InReplyTo.Valid {
+			exported.InReplyTo = &chirp.InReplyTo.UUID
+		}
+		if chirp.RechirpOf.Valid {
+			exported.RechirpOf = &chirp.RechirpOf.UUID
+		}
+		if chirp.QuoteOf.Valid {
+			exported.QuoteOf = &chirp.QuoteOf.UUID
+		}
+		exportedChirps = append(exportedChirps, exported)
+	}
+	exportedLikes := make([]exportedLike, 0, len(likes))
+	for _, like := range likes {
+		exportedLikes = append(exportedLikes, exportedLike{ChirpID: like.ChirpID, CreatedAt: like.CreatedAt})
+	}
+	follows := exportedFollows{
+		Following: make([]exportedFollow, 0, len(following)),
+		Followers: make([]exportedFollow, 0, len(followers)),
+	}
+	for _, follow := range following {
+		follows.Following = append(follows.Following, exportedFollow{UserID: follow.FolloweeID, CreatedAt: follow.CreatedAt})
+	}
+	for _, follow := range followers {
+		follows.Followers = append(follows.Followers, exportedFollow{UserID: follow.FollowerID, CreatedAt: follow.CreatedAt})
+	}
+	sessions := make([]exportedSession, 0, len(refreshTokens))
+	for _, token := range refreshTokens {
+		session := exportedSession{
+			SessionID: token.FamilyID,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			UserAgent: token.UserAgent,
+			IPAddress: token.IpAddress,
+		}
+		if token.RevokedAt.Valid {
+			session.RevokedAt = &token.RevokedAt.Time
+		}
+		sessions = append(sessions, session)
+	}
+
+	err = os.MkdirAll(c.exportDir, 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create the export directory: %v", err)
+	}
+	tempPath := archivePath + ".tmp"
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create the archive: %v", err)
+	}
+	defer os.Remove(tempPath)
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", newUsersResponse(userData)},
+		{"chirps.json", exportedChirps},
+		{"likes.json", exportedLikes},
+		{"follows.json", follows},
+		{"sessions.json", sessions},
+	}
+	for _, f := range files {
+		err = writeJSONToZip(archive, f.name, f.data)
+		if err != nil {
+			return fmt.Errorf("failed to write %v: %v", f.name, err)
+		}
+	}
+	err = archive.Close()
+	if err != nil {
+		return fmt.Errorf("failed to finish the archive: %v", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to finish the archive: %v", err)
+	}
+	return os.Rename(tempPath, archivePath)
+}
+
+func writeJSONToZip(archive *zip.Writer, name string, data any) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// failInterruptedDataExports marks exports left pending by a previous run as
+// failed. They can never finish, and would keep their users from asking for
+// a new one.
+func (c *apiConfig) failInterruptedDataExports() error {
+	return c.db.FailPendingDataExports(context.Background())
+}
+
+// expireDataExports deletes exports past their lifetime, once at startup and
+// then every hour, so a process restarted more often than that still gets to
+// it. Archives are swept from the directory by age rather than by row, so the
+// ones whose user was deleted in the meantime go too.
+func (c *apiConfig) expireDataExports() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		c.deleteExpiredDataExports()
+		<-ticker.C
+	}
+}
+
+func (c *apiConfig) deleteExpiredDataExports() {
+	err := c.db.DeleteExpiredDataExports(context.Background(), time.Now())
+	if err != nil {
+		fmt.Printf("[Error]: Failed to delete expired data exports: %v\n", err)
+	}
+	entries, err := os.ReadDir(c.exportDir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("[Error]: Failed to read the export directory: %v\n", err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < dataExportLifetime {
+			continue
+		}
+		err = os.Remove(filepath.Join(c.exportDir, entry.Name()))
+		if err != nil {
+			fmt.Printf("[Error]: Failed to remove data export %v: %v\n", entry.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/Mr-Rafael/chirpy/internal/mailer"
+	"github.com/google/uuid"
+)
+
+const emailVerificationTokenLifetime = 24 * time.Hour
+
+// Resending is capped per user so it can't be used to flood an inbox.
+var emailVerificationResendThrottle = throttlePolicy{backoffAfter: 3, lockoutAfter: 5, lockout: 1 * time.Hour}
+
+var errEmailVerificationTokenUsed = errors.New("the verification token was used by a concurrent request")
+
+// isValidEmail accepts a bare address like user@example.com, without a
+// display name or angle brackets.
+func isValidEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// sendEmailVerification mails a link that proves the user controls email.
+// Following it marks the address verified and, if it differs from the
+// account's current one, switches the account over to it.
+func (c *apiConfig) sendEmailVerification(userID uuid.UUID, email string) error {
+	token, err := auth.MakeOpaqueToken()
+	if err != nil {
+		return err
+	}
+	createParams := database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().Add(emailVerificationTokenLifetime),
+	}
+	err = c.db.CreateEmailVerificationToken(context.Background(), createParams)
+	if err != nil {
+		return fmt.Errorf("failed to save the verification token: %v", err)
+	}
+
+	verifyLink := c.publicURL + "/api/verify-email?token=" + url.QueryEscape(token)
+	return c.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Confirm your email for Chirpy",
+		Body: fmt.Sprintf("Open this link within the next 24 hours to confirm this address for your Chirpy account:\n%v\n\n"+
+			"If you didn't ask for this, you can ignore this email.\n", verifyLink),
+	})
+}
+
+type emailVerificationResendResponseParams struct {
+	Email string `json:"email"`
+}
+
+// handlerResendEmailVerification mails a new verification link, for when the
+// first one got lost or expired. It goes to the address waiting to replace the
+// current one if there is one, and otherwise to the current address if it
+// isn't verified yet. Older links stop working.
+func (c *apiConfig) handlerResendEmailVerification(writer http.ResponseWriter, request *http.Request) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userData, err := c.db.GetUserByID(context.Background(), jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get user data: %v", err), "User not found", http.StatusNotFound)
+		return
+	}
+	email := ""
+	tokenData, err := c.db.GetLatestEmailVerificationToken(context.Background(), jwt_user_id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(writer, fmt.Sprintf("Failed to get the pending verification: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if err == nil && tokenData.Email != userData.Email {
+		email = tokenData.Email
+	} else if !userData.EmailVerifiedAt.Valid {
+		email = userData.Email
+	}
+	if email == "" {
+		respondWithError(writer, "Verification resend for a verified email with no change pending.", "The email is already verified", http.StatusConflict)
+		return
+	}
+
+	_, retryAfter, err := c.countLoginAttempt(emailVerificationResendThrottle, "verify:"+jwt_user_id.String())
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to check verification resend throttling: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if retryAfter > 0 {
+		respondWithTooManyRequests(writer, retryAfter)
+		return
+	}
+
+	err = c.db.InvalidateEmailVerificationTokens(context.Background(), jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to invalidate the old verification tokens: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	err = c.sendEmailVerification(jwt_user_id, email)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to send the verification email: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(writer, emailVerificationResendResponseParams{Email: email}, http.StatusAccepted)
+}
+
+func (c *apiConfig) handlerVerifyEmail(writer http.ResponseWriter, request *http.Request) {
+	token := request.URL.Query().Get("token")
+	if token == "" {
+		respondWithError(writer, "The verification token came empty.", "Missing param: token", http.StatusBadRequest)
+		return
+	}
+
+	tokenData, err := c.db.GetEmailVerificationToken(context.Background(), auth.HashToken(token))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("The verification token wasn't found: %v", err), "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	if tokenData.UsedAt.Valid {
+		respondWithError(writer, fmt.Sprintf("The verification token was already used on %v", tokenData.UsedAt.Time), "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	if tokenData.ExpiresAt.Before(time.Now()) {
+		respondWithError(writer, fmt.Sprintf("The verification token expired on %v", tokenData.ExpiresAt), "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	userData, err := c.verifyEmail(tokenData)
+	if isUniqueViolation(err) {
+		respondWithError(writer, fmt.Sprintf("Failed to verify the email: %v", err), "Email already taken", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, errEmailVerificationTokenUsed) {
+		respondWithError(writer, err.Error(), "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to verify the email: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(writer, newUsersResponse(userData), http.StatusOK)
+}
+
+// verifyEmail uses up the token and moves the account to the verified
+// address. Any other pending verification for the user is cancelled, so an
+// older email change can't be confirmed after a newer one.
+func (c *apiConfig) verifyEmail(tokenData database.EmailVerificationToken) (database.User, error) {
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return database.User{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	used, err := qtx.UseEmailVerificationToken(context.Background(), tokenData.TokenHash)
+	if err != nil {
+		return database.User{}, fmt.Errorf("failed to use the verification token: %v", err)
+	}
+	if used == 0 {
+		return database.User{}, errEmailVerificationTokenUsed
+	}
+	userData, err := qtx.VerifyUserEmail(context.Background(), database.VerifyUserEmailParams{
+		ID:    tokenData.UserID,
+		Email: tokenData.Email,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+	err = qtx.InvalidateEmailVerificationTokens(context.Background(), tokenData.UserID)
+	if err != nil {
+		return database.User{}, fmt.Errorf("failed to invalidate other verification tokens: %v", err)
+	}
+	return userData, tx.Commit()
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type followResponseParams struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt string    `json:"followed_at"`
+}
+
+func (c *apiConfig) handlerFollowPOST(writer http.ResponseWriter, request *http.Request) {
+	followeeID, err := uuid.Parse(request.PathValue("user_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the user id: %v", err), "Invalid User ID", http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if followeeID == jwt_user_id {
+		respondWithError(writer, "A user tried to follow themselves.", "You can't follow yourself", http.StatusBadRequest)
+		return
+	}
+	_, err = c.db.GetUserByID(context.Background(), followeeID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get user data: %v", err), "User not found", http.StatusNotFound)
+		return
+	}
+	blocked, err := c.isBlockedBetween(uuid.NullUUID{UUID: jwt_user_id, Valid: true}, followeeID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error checking blocks: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if blocked {
+		respondWithError(writer, "A user tried to follow a user they blocked or were blocked by.", "You can't follow this user", http.StatusForbidden)
+		return
+	}
+
+	followUserParams := database.FollowUserParams{
+		FollowerID: jwt_user_id,
+		FolloweeID: followeeID,
+	}
+	err = c.db.FollowUser(context.Background(), followUserParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to save the follow to the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) handlerFollowDELETE(writer http.ResponseWriter, request *http.Request) {
+	followeeID, err := uuid.Parse(request.PathValue("user_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the user id: %v", err), "Invalid User ID", http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	unfollowUserParams := database.UnfollowUserParams{
+		FollowerID: jwt_user_id,
+		FolloweeID: followeeID,
+	}
+	err = c.db.UnfollowUser(context.Background(), unfollowUserParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to delete the follow from the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) handlerFollowersGET(writer http.ResponseWriter, request *http.Request) {
+	userID, err := uuid.Parse(request.PathValue("user_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the user id: %v", err), "Invalid User ID", http.StatusNotFound)
+		return
+	}
+
+	pageParams, err := parseFollowPageParams(request)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the pagination params: %v", err), "Invalid pagination params", http.StatusBadRequest)
+		return
+	}
+
+	var queryResult []database.Follow
+	cursorCreatedAt, cursorID := pageParams.cursorArgs()
+	if pageParams.queryDesc() {
+		queryResult, err = c.db.ListFollowersDesc(context.Background(), database.ListFollowersDescParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           pageParams.queryLimit(),
+		})
+	} else {
+		queryResult, err = c.db.ListFollowersAsc(context.Background(), database.ListFollowersAscParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           pageParams.queryLimit(),
+		})
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting followers from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	respondWithFollowPage(writer, request, pageParams, queryResult, func(follow database.Follow) uuid.UUID {
+		return follow.FollowerID
+	})
+}
+
+func (c *apiConfig) handlerFollowingGET(writer http.ResponseWriter, request *http.Request) {
+	userID, err := uuid.Parse(request.PathValue("user_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the user id: %v", err), "Invalid User ID", http.StatusNotFound)
+		return
+	}
+
+	pageParams, err := parseFollowPageParams(request)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the pagination params: %v", err), "Invalid pagination params", http.StatusBadRequest)
+		return
+	}
+
+	var queryResult []database.Follow
+	cursorCreatedAt, cursorID := pageParams.cursorArgs()
+	if pageParams.queryDesc() {
+		queryResult, err = c.db.ListFollowingDesc(context.Background(), database.ListFollowingDescParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           pageParams.queryLimit(),
+		})
+	} else {
+		queryResult, err = c.db.ListFollowingAsc(context.Background(), database.ListFollowingAscParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           pageParams.queryLimit(),
+		})
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting followed users from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	respondWithFollowPage(writer, request, pageParams, queryResult, func(follow database.Follow) uuid.UUID {
+		return follow.FolloweeID
+	})
+}
+
+// parseFollowPageParams reads the pagination params for a follow list, which
+// reads newest first unless asked otherwise.
+func parseFollowPageParams(request *http.Request) (pageQuery, error) {
+	pageParams, err := parsePageParams(request.URL.Query())
+	if err != nil {
+		return pageParams, err
+	}
+	pageParams.Desc = request.URL.Query().Get("sort") != "asc"
+	return pageParams, nil
+}
+
+// respondWithFollowPage sends one page of a follow list. userIDOf picks the
+// user on the other side of each follow, whose id also keys the cursor.
+func respondWithFollowPage(writer http.ResponseWriter, request *http.Request, pageParams pageQuery, follows []database.Follow, userIDOf func(database.Follow) uuid.UUID) {
+	follows, nextCursor, prevCursor := paginate(pageParams, follows, func(follow database.Follow) pageCursor {
+		return pageCursor{CreatedAt: follow.CreatedAt, ID: userIDOf(follow)}
+	})
+	setPageLinks(writer, request, pageParams, nextCursor, prevCursor)
+
+	responseData := []followResponseParams{}
+	for _, follow := range follows {
+		responseData = append(responseData, followResponseParams{
+			UserID:     userIDOf(follow),
+			FollowedAt: follow.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	respondWithJSON(writer, responseData, http.StatusOK)
+}
+
+func (c *apiConfig) handlerTimelineGET(writer http.ResponseWriter, request *http.Request) {
+	user_id, err := c.authenticate(request, scopeChirpsRead)
+	if err != nil {
+		respondWithAuthError(writer, err)
+		return
+	}
+
+	pageParams, err := parsePageParams(request.URL.Query())
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the pagination params: %v", err), "Invalid pagination params", http.StatusBadRequest)
+		return
+	}
+	// Timelines read newest first unless asked otherwise.
+	pageParams.Desc = request.URL.Query().Get("sort") != "asc"
+
+	// Muted users are left out here, and only here; see handlerMutePOST.
+	var queryResult []chirpRow
+	cursorCreatedAt, cursorID := pageParams.cursorArgs()
+	if pageParams.queryDesc() {
+		queryResult, err = toChirpRows(c.db.ListTimelineDesc(context.Background(), database.ListTimelineDescParams{
+			FollowerID:      user_id,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           pageParams.queryLimit(),
+		}))
+	} else {
+		queryResult, err = toChirpRows(c.db.ListTimelineAsc(context.Background(), database.ListTimelineAscParams{
+			FollowerID:      user_id,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           pageParams.queryLimit(),
+		}))
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting the timeline from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	c.respondWithChirpPage(writer, request, pageParams, queryResult)
+}
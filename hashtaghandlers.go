@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Mr-Rafael/chirpy/internal/database"
+)
+
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+
+// extractHashtags returns the distinct, lowercased hashtags in a chirp body,
+// in the order they first appear.
+func extractHashtags(text string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		tag := strings.ToLower(match[1])
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func (c *apiConfig) handlerHashtagChirpsGET(writer http.ResponseWriter, request *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(request.PathValue("tag"), "#"))
+	if len(tag) == 0 {
+		respondWithError(writer, "The hashtag came empty.", "Missing param: tag", http.StatusBadRequest)
+		return
+	}
+
+	pageParams, err := parsePageParams(request.URL.Query())
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the pagination params: %v", err), "Invalid pagination params", http.StatusBadRequest)
+		return
+	}
+
+	var queryResult []chirpRow
+	cursorCreatedAt, cursorID := pageParams.cursorArgs()
+	if pageParams.queryDesc() {
+		queryResult, err = toChirpRows(c.db.ListHashtagChirpsDesc(context.Background(), database.ListHashtagChirpsDescParams{
+			Tag:             tag,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			ViewerID:        c.optionalUserID(request),
+			Limit:           pageParams.queryLimit(),
+		}))
+	} else {
+		queryResult, err = toChirpRows(c.db.ListHashtagChirpsAsc(context.Background(), database.ListHashtagChirpsAscParams{
+			Tag:             tag,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			ViewerID:        c.optionalUserID(request),
+			Limit:           pageParams.queryLimit(),
+		}))
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting hashtag chirps from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	c.respondWithChirpPage(writer, request, pageParams, queryResult)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "none", text: "just a chirp", want: []string{}},
+		{name: "order of first appearance", text: "#zebra then #apple and #Mango", want: []string{"zebra", "apple", "mango"}},
+		{name: "duplicates in any case", text: "#Go #go #GO", want: []string{"go"}},
+		{name: "punctuation ends a tag", text: "loving #golang, and #sql!", want: []string{"golang", "sql"}},
+		{name: "underscores and digits", text: "#web_dev #100days", want: []string{"web_dev", "100days"}},
+		{name: "unicode letters", text: "#café #日本", want: []string{"café", "日本"}},
+		{name: "inside a word", text: "email me at a#b or c#d", want: []string{}},
+		{name: "HTML entities", text: "it&#39;s fine", want: []string{}},
+		{name: "double hash", text: "##twice", want: []string{}},
+		{name: "lone hash", text: "# not a tag", want: []string{}},
+		{name: "after an opening bracket", text: "(#inside)", want: []string{"inside"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := extractHashtags(test.text)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Expected %q, got %q.", test.want, got)
+			}
+		})
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"time"
 
 	"crypto/rand"
+	"crypto/sha256"
 
 	"encoding/base64"
+	"encoding/hex"
 
 	"github.com/alexedwards/argon2id"
 	"github.com/golang-jwt/jwt/v5"
@@ -31,26 +33,60 @@ func CheckPasswordHash(password string, hash string) (bool, error) {
 	return match, nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+// ValidatorConfig describes the tokens a deployment accepts. Tokens must be
+// issued by Issuer for at least one of Audiences, carry every claim in
+// RequiredClaims and, when MaxTokenAge is set, have been issued no longer ago
+// than that. Leeway absorbs clock skew between the minting and checking hosts.
+type ValidatorConfig struct {
+	Issuer         string
+	Audiences      []string
+	Leeway         time.Duration
+	RequiredClaims []string
+	MaxTokenAge    time.Duration
+}
+
+// MakeJWT signs a token with the key set's current signing key. The key id
+// goes in the header so verifiers know which public key to check it against.
+func MakeJWT(userID uuid.UUID, keys *KeySet, issuer string, audience string, expiresIn time.Duration) (string, error) {
+	kid, signingKey, err := keys.signingKeyPair()
+	if err != nil {
+		return "", err
+	}
 	claims := jwt.RegisteredClaims{
 		Subject:   userID.String(),
-		Issuer:    "chirpy",
+		Issuer:    issuer,
+		Audience:  jwt.ClaimStrings{audience},
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(tokenSecret))
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(signingKey)
 }
 
-func ValidateJWT(tokenString string, tokenSecret string) (uuid.UUID, error) {
+func ValidateJWT(tokenString string, keys *KeySet, config ValidatorConfig) (uuid.UUID, error) {
 	claims := &jwt.RegisteredClaims{}
 
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithLeeway(config.Leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if len(config.Audiences) > 0 {
+		options = append(options, jwt.WithAudience(config.Audiences...))
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		kid, ok := t.Header["kid"].(string)
+		if !ok {
+			return nil, fmt.Errorf("token has no key id")
 		}
-		return []byte(tokenSecret), nil
-	})
+		return keys.publicKey(kid)
+	}, options...)
 	if err != nil {
 		return uuid.Nil, err
 	}
@@ -59,6 +95,19 @@ func ValidateJWT(tokenString string, tokenSecret string) (uuid.UUID, error) {
 		return uuid.Nil, fmt.Errorf("invalid token")
 	}
 
+	err = checkRequiredClaims(claims, config.RequiredClaims)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if config.MaxTokenAge > 0 {
+		if claims.IssuedAt == nil {
+			return uuid.Nil, fmt.Errorf("token has no issued at claim to check its age against")
+		}
+		if time.Since(claims.IssuedAt.Time) > config.MaxTokenAge+config.Leeway {
+			return uuid.Nil, fmt.Errorf("token was issued on %v, more than %v ago", claims.IssuedAt.Time, config.MaxTokenAge)
+		}
+	}
+
 	returnUUID, err := uuid.Parse(claims.Subject)
 	if err != nil {
 		return uuid.Nil, err
@@ -67,6 +116,34 @@ func ValidateJWT(tokenString string, tokenSecret string) (uuid.UUID, error) {
 	return returnUUID, nil
 }
 
+func checkRequiredClaims(claims *jwt.RegisteredClaims, required []string) error {
+	for _, claim := range required {
+		present := false
+		switch claim {
+		case "iss":
+			present = claims.Issuer != ""
+		case "sub":
+			present = claims.Subject != ""
+		case "aud":
+			present = len(claims.Audience) > 0
+		case "exp":
+			present = claims.ExpiresAt != nil
+		case "nbf":
+			present = claims.NotBefore != nil
+		case "iat":
+			present = claims.IssuedAt != nil
+		case "jti":
+			present = claims.ID != ""
+		default:
+			return fmt.Errorf("unsupported required claim: %v", claim)
+		}
+		if !present {
+			return fmt.Errorf("token is missing the %v claim", claim)
+		}
+	}
+	return nil
+}
+
 func GenerateSecretKeyHS256() (string, error) {
 	key := make([]byte, 32)
 	_, err := rand.Read(key)
@@ -76,6 +153,41 @@ func GenerateSecretKeyHS256() (string, error) {
 	return base64.URLEncoding.EncodeToString(key), nil
 }
 
+// HashToken returns the digest refresh and personal access tokens are stored
+// under, so a leaked table can't be replayed as live credentials. The tokens
+// are 256 random bits, so a plain unsalted SHA-256 is enough.
+func HashToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
+
+// PersonalAccessTokenPrefix tells personal access tokens apart from JWTs in
+// the Authorization header, and makes leaked ones easy to scan for.
+const PersonalAccessTokenPrefix = "chirpy_pat_"
+
+func MakePersonalAccessToken() (string, error) {
+	token, err := MakeOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalAccessTokenPrefix + token, nil
+}
+
+// MakeOpaqueToken returns 256 random bits, URL safe, for tokens that are
+// looked up by their HashToken digest rather than verified by signature.
+func MakeOpaqueToken() (string, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
 func GetBearerToken(headers http.Header) (string, error) {
 	bearer := headers.Get("Authorization")
 	if bearer == "" {
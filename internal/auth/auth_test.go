@@ -1,27 +1,63 @@
 package auth
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+var testValidatorConfig = ValidatorConfig{
+	Issuer:         "chirpy",
+	Audiences:      []string{"chirpy-api"},
+	Leeway:         30 * time.Second,
+	RequiredClaims: []string{"iss", "sub", "aud", "exp", "iat"},
+	MaxTokenAge:    1 * time.Hour,
+}
+
+func signTestClaims(t *testing.T, keys *KeySet, claims jwt.RegisteredClaims) string {
+	kid, signing_key, err := keys.signingKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to get the signing key: %v", err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = kid
+	jwt_token, err := token.SignedString(signing_key)
+	if err != nil {
+		t.Fatalf("Failed to sign the JWT: %v", err)
+	}
+	return jwt_token
+}
+
+func testClaims(issuedAt time.Time, expiresAt time.Time) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   uuid.New().String(),
+		Issuer:    "chirpy",
+		Audience:  jwt.ClaimStrings{"chirpy-api"},
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(issuedAt),
+	}
+}
+
 func TestValidJWT(t *testing.T) {
 	test_uuid := uuid.New()
-	secret_key, err := GenerateSecretKeyHS256()
+	keys, err := LoadKeySet(t.TempDir())
 	if err != nil {
-		t.Errorf("Failed to generate a secret HS256 key for signing the jwt: %v", err)
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
 	}
 	duration := 1 * time.Hour
 
-	jwt_token, err := MakeJWT(test_uuid, secret_key, duration)
+	jwt_token, err := MakeJWT(test_uuid, keys, "chirpy", "chirpy-api", duration)
 	if err != nil {
 		t.Errorf("Failed to generate a JWT: %v", err)
 	}
 
-	result, err := ValidateJWT(jwt_token, secret_key)
+	result, err := ValidateJWT(jwt_token, keys, testValidatorConfig)
 	if err != nil {
 		t.Errorf("Failed to validate the JWT: %v", err)
 	}
@@ -32,22 +68,22 @@ func TestValidJWT(t *testing.T) {
 
 func TestWrongKeyJWT(t *testing.T) {
 	test_uuid := uuid.New()
-	correct_key, err := GenerateSecretKeyHS256()
+	correct_keys, err := LoadKeySet(t.TempDir())
 	if err != nil {
-		t.Errorf("Failed to generate a secret HS256 key for signing the jwt: %v", err)
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
 	}
-	incorrect_key, err := GenerateSecretKeyHS256()
+	incorrect_keys, err := LoadKeySet(t.TempDir())
 	if err != nil {
-		t.Errorf("Failed to generate a secret HS256 key for signing the jwt: %v", err)
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
 	}
 	duration := 1 * time.Hour
 
-	jwt_token, err := MakeJWT(test_uuid, incorrect_key, duration)
+	jwt_token, err := MakeJWT(test_uuid, incorrect_keys, "chirpy", "chirpy-api", duration)
 	if err != nil {
 		t.Errorf("failed to generate a JWT: %v", err)
 	}
 
-	_, err = ValidateJWT(jwt_token, correct_key)
+	_, err = ValidateJWT(jwt_token, correct_keys, testValidatorConfig)
 	if err == nil {
 		t.Errorf("Expected the JWT to be invalid, but was valid.")
 	}
@@ -55,24 +91,348 @@ func TestWrongKeyJWT(t *testing.T) {
 
 func TestExpiredJWT(t *testing.T) {
 	test_uuid := uuid.New()
-	correct_key, err := GenerateSecretKeyHS256()
+	keys, err := LoadKeySet(t.TempDir())
 	if err != nil {
-		t.Errorf("Failed to generate a secret HS256 key for signing the jwt: %v", err)
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
 	}
-	incorrect_key, err := GenerateSecretKeyHS256()
-	if err != nil {
-		t.Errorf("Failed to generate a secret HS256 key for signing the jwt: %v", err)
-	}
-	duration := 1 * time.Second
-	time.Sleep(3 * time.Second)
+	// Expired for longer than the validator's leeway.
+	duration := -1 * time.Minute
 
-	jwt_token, err := MakeJWT(test_uuid, incorrect_key, duration)
+	jwt_token, err := MakeJWT(test_uuid, keys, "chirpy", "chirpy-api", duration)
 	if err != nil {
 		t.Errorf("failed to generate a JWT: %v", err)
 	}
 
-	_, err = ValidateJWT(jwt_token, correct_key)
+	_, err = ValidateJWT(jwt_token, keys, testValidatorConfig)
 	if err == nil {
 		t.Errorf("Expected the JWT to be invalid, but was valid.")
 	}
 }
+
+func TestRotatedKeyJWT(t *testing.T) {
+	test_uuid := uuid.New()
+	key_dir := t.TempDir()
+	keys, err := LoadKeySet(key_dir)
+	if err != nil {
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
+	}
+	duration := 1 * time.Hour
+
+	old_token, err := MakeJWT(test_uuid, keys, "chirpy", "chirpy-api", duration)
+	if err != nil {
+		t.Errorf("Failed to generate a JWT: %v", err)
+	}
+	_, err = keys.Rotate()
+	if err != nil {
+		t.Errorf("Failed to rotate the signing key: %v", err)
+	}
+	new_token, err := MakeJWT(test_uuid, keys, "chirpy", "chirpy-api", duration)
+	if err != nil {
+		t.Errorf("Failed to generate a JWT: %v", err)
+	}
+
+	reloaded_keys, err := LoadKeySet(key_dir)
+	if err != nil {
+		t.Errorf("Failed to reload the key set: %v", err)
+	}
+	if len(reloaded_keys.JWKS().Keys) != 2 {
+		t.Errorf("Expected 2 published keys, got %v.", len(reloaded_keys.JWKS().Keys))
+	}
+	for _, token := range []string{old_token, new_token} {
+		_, err = ValidateJWT(token, reloaded_keys, testValidatorConfig)
+		if err != nil {
+			t.Errorf("Expected the JWT to stay valid after rotation: %v", err)
+		}
+	}
+}
+
+func TestKeyRotatedByOtherInstanceJWT(t *testing.T) {
+	test_uuid := uuid.New()
+	key_dir := t.TempDir()
+	keys, err := LoadKeySet(key_dir)
+	if err != nil {
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
+	}
+	other_keys, err := LoadKeySet(key_dir)
+	if err != nil {
+		t.Errorf("Failed to load the other instance's key set: %v", err)
+	}
+	_, err = other_keys.Rotate()
+	if err != nil {
+		t.Errorf("Failed to rotate the signing key: %v", err)
+	}
+	jwt_token, err := MakeJWT(test_uuid, other_keys, "chirpy", "chirpy-api", 1*time.Hour)
+	if err != nil {
+		t.Errorf("Failed to generate a JWT: %v", err)
+	}
+
+	_, err = ValidateJWT(jwt_token, keys, testValidatorConfig)
+	if err == nil {
+		t.Errorf("Expected the unknown key to be rejected right after a reload.")
+	}
+	keys.lastReload = time.Now().Add(-minReloadInterval)
+	result, err := ValidateJWT(jwt_token, keys, testValidatorConfig)
+	if err != nil {
+		t.Errorf("Expected the key to be picked up from the directory: %v", err)
+	}
+	if result != test_uuid {
+		t.Errorf("Expected %v, got %v.", test_uuid, result)
+	}
+}
+
+func TestHandNamedKey(t *testing.T) {
+	key_dir := t.TempDir()
+	keys, err := LoadKeySet(key_dir)
+	if err != nil {
+		t.Fatalf("Failed to load a key set: %v", err)
+	}
+	signing_kid, _, err := keys.signingKeyPair()
+	if err != nil {
+		t.Fatalf("Failed to get the signing key: %v", err)
+	}
+
+	// A key named by hand must not win just because letters sort after digits.
+	data, err := os.ReadFile(filepath.Join(key_dir, signing_kid+".pem"))
+	if err != nil {
+		t.Fatalf("Failed to read the signing key: %v", err)
+	}
+	hand_named := filepath.Join(key_dir, "prod.pem")
+	err = os.WriteFile(hand_named, data, 0600)
+	if err != nil {
+		t.Fatalf("Failed to write the hand-named key: %v", err)
+	}
+	old_time := time.Now().Add(-72 * time.Hour)
+	err = os.Chtimes(hand_named, old_time, old_time)
+	if err != nil {
+		t.Fatalf("Failed to age the hand-named key: %v", err)
+	}
+
+	err = keys.Reload()
+	if err != nil {
+		t.Fatalf("Failed to reload the key set: %v", err)
+	}
+	kid, _, _ := keys.signingKeyPair()
+	if kid != signing_kid {
+		t.Errorf("Expected %v to keep signing, got %v.", signing_kid, kid)
+	}
+	err = keys.Prune(24 * time.Hour)
+	if err != nil {
+		t.Errorf("Failed to prune the keys: %v", err)
+	}
+	if _, err = os.Stat(hand_named); !os.IsNotExist(err) {
+		t.Errorf("Expected the old hand-named key to be pruned: %v", err)
+	}
+
+	// On its own it signs, and its file time makes it due for rotation.
+	err = os.Remove(filepath.Join(key_dir, signing_kid+".pem"))
+	if err != nil {
+		t.Fatalf("Failed to remove the signing key: %v", err)
+	}
+	err = os.WriteFile(hand_named, data, 0600)
+	if err != nil {
+		t.Fatalf("Failed to write the hand-named key: %v", err)
+	}
+	err = os.Chtimes(hand_named, old_time, old_time)
+	if err != nil {
+		t.Fatalf("Failed to age the hand-named key: %v", err)
+	}
+	err = keys.Reload()
+	if err != nil {
+		t.Fatalf("Failed to reload the key set: %v", err)
+	}
+	if keys.SigningKeyAge() < 71*time.Hour {
+		t.Errorf("Expected the signing key age to come from the file time, got %v.", keys.SigningKeyAge())
+	}
+}
+
+func TestWrongIssuerJWT(t *testing.T) {
+	keys, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
+	}
+
+	jwt_token, err := MakeJWT(uuid.New(), keys, "other-deployment", "chirpy-api", 1*time.Hour)
+	if err != nil {
+		t.Errorf("Failed to generate a JWT: %v", err)
+	}
+
+	_, err = ValidateJWT(jwt_token, keys, testValidatorConfig)
+	if err == nil {
+		t.Errorf("Expected a JWT from another issuer to be invalid, but was valid.")
+	}
+}
+
+func TestWrongAudienceJWT(t *testing.T) {
+	keys, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
+	}
+
+	jwt_token, err := MakeJWT(uuid.New(), keys, "chirpy", "chirpy-webhooks", 1*time.Hour)
+	if err != nil {
+		t.Errorf("Failed to generate a JWT: %v", err)
+	}
+
+	_, err = ValidateJWT(jwt_token, keys, testValidatorConfig)
+	if err == nil {
+		t.Errorf("Expected a JWT for another audience to be invalid, but was valid.")
+	}
+}
+
+func TestLeewayJWT(t *testing.T) {
+	keys, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
+	}
+	now := time.Now()
+
+	within_leeway := signTestClaims(t, keys, testClaims(now.Add(-10*time.Minute), now.Add(-10*time.Second)))
+	_, err = ValidateJWT(within_leeway, keys, testValidatorConfig)
+	if err != nil {
+		t.Errorf("Expected a JWT expired within the leeway to be valid: %v", err)
+	}
+
+	past_leeway := signTestClaims(t, keys, testClaims(now.Add(-10*time.Minute), now.Add(-1*time.Minute)))
+	_, err = ValidateJWT(past_leeway, keys, testValidatorConfig)
+	if err == nil {
+		t.Errorf("Expected a JWT expired past the leeway to be invalid, but was valid.")
+	}
+
+	issued_in_future := signTestClaims(t, keys, testClaims(now.Add(5*time.Minute), now.Add(1*time.Hour)))
+	_, err = ValidateJWT(issued_in_future, keys, testValidatorConfig)
+	if err == nil {
+		t.Errorf("Expected a JWT issued in the future to be invalid, but was valid.")
+	}
+}
+
+func TestRequiredClaimsJWT(t *testing.T) {
+	keys, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
+	}
+	now := time.Now()
+
+	claims := testClaims(now, now.Add(1*time.Hour))
+	claims.Audience = nil
+	no_audience := signTestClaims(t, keys, claims)
+	config := testValidatorConfig
+	config.Audiences = nil
+	_, err = ValidateJWT(no_audience, keys, config)
+	if err == nil {
+		t.Errorf("Expected a JWT without an audience to be invalid, but was valid.")
+	}
+
+	claims = testClaims(now, now.Add(1*time.Hour))
+	claims.ExpiresAt = nil
+	no_expiry := signTestClaims(t, keys, claims)
+	_, err = ValidateJWT(no_expiry, keys, testValidatorConfig)
+	if err == nil {
+		t.Errorf("Expected a JWT without an expiry to be invalid, but was valid.")
+	}
+}
+
+func TestMaxTokenAgeJWT(t *testing.T) {
+	keys, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
+	}
+	now := time.Now()
+
+	long_lived := signTestClaims(t, keys, testClaims(now.Add(-2*time.Hour), now.Add(24*time.Hour)))
+	_, err = ValidateJWT(long_lived, keys, testValidatorConfig)
+	if err == nil {
+		t.Errorf("Expected a JWT older than the max token age to be invalid, but was valid.")
+	}
+
+	config := testValidatorConfig
+	config.MaxTokenAge = 0
+	_, err = ValidateJWT(long_lived, keys, config)
+	if err != nil {
+		t.Errorf("Expected the JWT to be valid without a max token age: %v", err)
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	token, err := GenerateSecretKeyHS256()
+	if err != nil {
+		t.Errorf("Failed to generate a refresh token: %v", err)
+	}
+	other_token, err := GenerateSecretKeyHS256()
+	if err != nil {
+		t.Errorf("Failed to generate a refresh token: %v", err)
+	}
+
+	hash := HashToken(token)
+	if hash == token {
+		t.Errorf("Expected the hash to differ from the token.")
+	}
+	if hash != HashToken(token) {
+		t.Errorf("Expected hashing the same token twice to match.")
+	}
+	if hash == HashToken(other_token) {
+		t.Errorf("Expected different tokens to have different hashes.")
+	}
+}
+
+func TestPersonalAccessToken(t *testing.T) {
+	token, err := MakePersonalAccessToken()
+	if err != nil {
+		t.Errorf("Failed to generate a personal access token: %v", err)
+	}
+	if !IsPersonalAccessToken(token) {
+		t.Errorf("Expected %v to be recognized as a personal access token.", token)
+	}
+
+	keys, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Errorf("Failed to load a key set for signing the jwt: %v", err)
+	}
+	jwt_token, err := MakeJWT(uuid.New(), keys, "chirpy", "chirpy-api", 1*time.Hour)
+	if err != nil {
+		t.Errorf("Failed to generate a JWT: %v", err)
+	}
+	if IsPersonalAccessToken(jwt_token) {
+		t.Errorf("Expected a JWT not to be recognized as a personal access token.")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 SHA-1 test vectors, truncated to six digits.
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+	}
+	for unix_time, code := range vectors {
+		_, ok := ValidateTOTP(secret, code, time.Unix(unix_time, 0))
+		if !ok {
+			t.Errorf("Expected code %v to be valid at %v.", code, unix_time)
+		}
+	}
+
+	_, ok := ValidateTOTP(secret, "287082", time.Unix(59+5*30, 0))
+	if ok {
+		t.Errorf("Expected a code from five periods ago to be invalid, but was valid.")
+	}
+	_, ok = ValidateTOTP(secret, "000000", time.Unix(59, 0))
+	if ok {
+		t.Errorf("Expected a wrong code to be invalid, but was valid.")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Errorf("Failed to generate recovery codes: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("Expected %v recovery codes, got %v.", recoveryCodeCount, len(codes))
+	}
+	if NormalizeRecoveryCode(strings.ToUpper(codes[0])) != NormalizeRecoveryCode(codes[0]) {
+		t.Errorf("Expected recovery codes to be case insensitive.")
+	}
+	if codes[0] == codes[1] {
+		t.Errorf("Expected recovery codes to differ.")
+	}
+}
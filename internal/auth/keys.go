@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// kidLayout names keys after the moment they were created, so a key's age can
+// be read back from its id. A random suffix keeps two keys created in the same
+// second apart.
+const kidLayout = "20060102T150405Z"
+
+// minReloadInterval limits how often a token with an unknown kid makes the
+// key set re-read the directory, so a stream of forged kids can't keep it
+// hitting the disk.
+const minReloadInterval = 5 * time.Second
+
+// KeySet holds the Ed25519 keys tokens are signed and verified with. Keys live
+// in a directory as <kid>.pem files: private keys can sign and verify, public
+// keys left behind by a retired key can only verify. The newest private key
+// signs new tokens.
+type KeySet struct {
+	mu         sync.RWMutex
+	dir        string
+	signingKID string
+	signingKey ed25519.PrivateKey
+	publicKeys map[string]ed25519.PublicKey
+	createdAt  map[string]time.Time
+
+	// reloadMu keeps a reload from racing a rotation or prune, which could
+	// drop a key that was just written.
+	reloadMu   sync.Mutex
+	lastReload time.Time
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet reads the keys in dir, creating the directory and a first
+// signing key if there are none yet.
+func LoadKeySet(dir string) (*KeySet, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the key directory: %v", err)
+	}
+	keys := &KeySet{dir: dir}
+	err = keys.Reload()
+	if err != nil {
+		return nil, err
+	}
+	if keys.signingKey == nil {
+		_, err = keys.Rotate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// Reload re-reads the key directory, picking up keys added or removed by
+// other instances or by an operator.
+func (k *KeySet) Reload() error {
+	k.reloadMu.Lock()
+	defer k.reloadMu.Unlock()
+	return k.reload()
+}
+
+func (k *KeySet) reload() error {
+	k.lastReload = time.Now()
+	entries, err := os.ReadDir(k.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read the key directory: %v", err)
+	}
+
+	var signingKID string
+	var signingKey ed25519.PrivateKey
+	publicKeys := map[string]ed25519.PublicKey{}
+	createdAt := map[string]time.Time{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".pem") {
+			continue
+		}
+		kid := strings.TrimSuffix(entry.Name(), ".pem")
+		data, err := os.ReadFile(filepath.Join(k.dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read key %v: %v", kid, err)
+		}
+		privateKey, publicKey, err := parseKey(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse key %v: %v", kid, err)
+		}
+		created, err := kidCreatedAt(kid)
+		if err != nil {
+			// A key an operator named by hand still needs an age, or it would
+			// never be rotated out or pruned; the file's mtime is the best guess.
+			info, infoErr := entry.Info()
+			if infoErr != nil {
+				return fmt.Errorf("failed to stat key %v: %v", kid, infoErr)
+			}
+			created = info.ModTime()
+			fmt.Printf("[Warning]: JWT key %v isn't named %v-<suffix> (%v); using its file time %v as its creation time\n", kid, kidLayout, err, created.UTC().Format(time.RFC3339))
+		}
+		publicKeys[kid] = publicKey
+		createdAt[kid] = created
+		if privateKey != nil && newerKey(kid, created, signingKID, createdAt[signingKID]) {
+			signingKID = kid
+			signingKey = privateKey
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.signingKID = signingKID
+	k.signingKey = signingKey
+	k.publicKeys = publicKeys
+	k.createdAt = createdAt
+	return nil
+}
+
+// Rotate generates a new private key, which signs every token from now on.
+// Older keys keep verifying the tokens they signed until they are pruned.
+func (k *KeySet) Rotate() (string, error) {
+	k.reloadMu.Lock()
+	defer k.reloadMu.Unlock()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate signing key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode signing key: %v", err)
+	}
+
+	suffix := make([]byte, 4)
+	_, err = rand.Read(suffix)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key id: %v", err)
+	}
+	created := time.Now().UTC().Truncate(time.Second)
+	kid := created.Format(kidLayout) + "-" + hex.EncodeToString(suffix)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	err = os.WriteFile(filepath.Join(k.dir, kid+".pem"), data, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to save signing key: %v", err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.signingKID = kid
+	k.signingKey = privateKey
+	k.publicKeys[kid] = publicKey
+	k.createdAt[kid] = created
+	return kid, nil
+}
+
+// Prune deletes keys created more than maxAge ago. The signing key is always
+// kept.
+func (k *KeySet) Prune(maxAge time.Duration) error {
+	k.reloadMu.Lock()
+	defer k.reloadMu.Unlock()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for kid := range k.publicKeys {
+		if kid == k.signingKID || time.Since(k.createdAt[kid]) <= maxAge {
+			continue
+		}
+		err := os.Remove(filepath.Join(k.dir, kid+".pem"))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove key %v: %v", kid, err)
+		}
+		delete(k.publicKeys, kid)
+		delete(k.createdAt, kid)
+	}
+	return nil
+}
+
+// SigningKeyAge reports how long the current signing key has been in use.
+func (k *KeySet) SigningKeyAge() time.Duration {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return time.Since(k.createdAt[k.signingKID])
+}
+
+// JWKS publishes the verification keys so other services can check tokens
+// without being able to mint them.
+func (k *KeySet) JWKS() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	kids := make([]string, 0, len(k.publicKeys))
+	for kid := range k.publicKeys {
+		kids = append(kids, kid)
+	}
+	sort.Slice(kids, func(i, j int) bool {
+		return newerKey(kids[i], k.createdAt[kids[i]], kids[j], k.createdAt[kids[j]])
+	})
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		jwks.Keys = append(jwks.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(k.publicKeys[kid]),
+			KeyID:     kid,
+			Algorithm: "EdDSA",
+			Use:       "sig",
+		})
+	}
+	return jwks
+}
+
+func (k *KeySet) signingKeyPair() (string, ed25519.PrivateKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.signingKey == nil {
+		return "", nil, fmt.Errorf("no signing key loaded")
+	}
+	return k.signingKID, k.signingKey, nil
+}
+
+// publicKey looks up the key that signed a token. A kid it doesn't know may
+// belong to a key another instance just rotated in, so it re-reads the key
+// directory once before giving up.
+func (k *KeySet) publicKey(kid string) (ed25519.PublicKey, error) {
+	publicKey, ok := k.lookupPublicKey(kid)
+	if ok {
+		return publicKey, nil
+	}
+	err := k.reloadIfStale()
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok = k.lookupPublicKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %v", kid)
+	}
+	return publicKey, nil
+}
+
+func (k *KeySet) lookupPublicKey(kid string) (ed25519.PublicKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	publicKey, ok := k.publicKeys[kid]
+	return publicKey, ok
+}
+
+// reloadIfStale reloads the keys unless that happened in the last
+// minReloadInterval.
+func (k *KeySet) reloadIfStale() error {
+	k.reloadMu.Lock()
+	defer k.reloadMu.Unlock()
+	if time.Since(k.lastReload) < minReloadInterval {
+		return nil
+	}
+	return k.reload()
+}
+
+func parseKey(data []byte) (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, fmt.Errorf("no PEM block found")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		privateKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, nil, fmt.Errorf("not an Ed25519 key")
+		}
+		return privateKey, privateKey.Public().(ed25519.PublicKey), nil
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, nil, fmt.Errorf("not an Ed25519 key")
+		}
+		return nil, publicKey, nil
+	}
+	return nil, nil, fmt.Errorf("unexpected PEM block type: %v", block.Type)
+}
+
+// kidCreatedAt reads the creation time out of a kid made by Rotate.
+func kidCreatedAt(kid string) (time.Time, error) {
+	timestamp, _, _ := strings.Cut(kid, "-")
+	return time.Parse(kidLayout, timestamp)
+}
+
+// newerKey orders keys newest first by creation time, falling back to the kid
+// so the order is stable when two keys share a timestamp.
+func newerKey(kid string, created time.Time, otherKID string, otherCreated time.Time) bool {
+	if otherKID == "" {
+		return true
+	}
+	if !created.Equal(otherCreated) {
+		return created.After(otherCreated)
+	}
+	return kid > otherKID
+}
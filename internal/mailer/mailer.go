@@ -0,0 +1,111 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends transactional mail. Handlers only depend on this interface, so
+// dev setups and tests can swap SMTP for something that keeps mail local.
+type Mailer interface {
+	Send(message Message) error
+}
+
+type SMTPMailer struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (m SMTPMailer) Send(message Message) error {
+	var smtpAuth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address %v: %v", m.Addr, err)
+		}
+		smtpAuth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	err := smtp.SendMail(m.Addr, smtpAuth, m.From, []string{message.To}, formatMessage(m.From, message))
+	if err != nil {
+		return fmt.Errorf("failed to send mail to %v: %v", message.To, err)
+	}
+	return nil
+}
+
+// FileMailer writes every message to Dir as an .eml file, or prints it when
+// Dir is empty. It also keeps the messages it sent, so tests can read them back.
+type FileMailer struct {
+	Dir  string
+	From string
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (m *FileMailer) Send(message Message) error {
+	data := formatMessage(m.From, message)
+	if m.Dir == "" {
+		fmt.Printf("[Mail]:\n%s\n", data)
+	} else {
+		err := os.MkdirAll(m.Dir, 0700)
+		if err != nil {
+			return fmt.Errorf("failed to create the mail directory: %v", err)
+		}
+		name := fmt.Sprintf("%v-%v.eml", time.Now().UTC().Format("20060102T150405.000000000Z"), sanitizeFileName(message.To))
+		err = os.WriteFile(filepath.Join(m.Dir, name), data, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to write mail to %v: %v", message.To, err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+func (m *FileMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
+
+func formatMessage(from string, message Message) []byte {
+	headers := []string{
+		"From: " + stripNewlines(from),
+		"To: " + stripNewlines(message.To),
+		"Subject: " + stripNewlines(message.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + message.Body)
+}
+
+// stripNewlines keeps user supplied values from injecting extra headers.
+func stripNewlines(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+func sanitizeFileName(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	mail_dir := t.TempDir()
+	mailer := &FileMailer{Dir: mail_dir, From: "chirpy@example.com"}
+
+	err := mailer.Send(Message{
+		To:      "user@example.com",
+		Subject: "Hello\r\nBcc: attacker@example.com",
+		Body:    "Hi there",
+	})
+	if err != nil {
+		t.Errorf("Failed to send the mail: %v", err)
+	}
+
+	if len(mailer.Sent()) != 1 {
+		t.Errorf("Expected 1 sent message, got %v.", len(mailer.Sent()))
+	}
+	files, err := os.ReadDir(mail_dir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected 1 mail file, got %v: %v", len(files), err)
+	}
+	data, err := os.ReadFile(mail_dir + "/" + files[0].Name())
+	if err != nil {
+		t.Errorf("Failed to read the mail file: %v", err)
+	}
+	if strings.Contains(string(data), "\r\nBcc:") {
+		t.Errorf("Expected newlines in headers to be stripped.")
+	}
+	if !strings.HasSuffix(string(data), "\r\n\r\nHi there") {
+		t.Errorf("Expected the body after the headers, got %q.", data)
+	}
+}
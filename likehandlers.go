@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (c *apiConfig) handlerLikePOST(writer http.ResponseWriter, request *http.Request) {
+	chirpID, err := uuid.Parse(request.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the chirp id: %v", err), "Invalid Chirp ID", http.StatusNotFound)
+		return
+	}
+
+	user_id, err := c.authenticate(request, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(writer, err)
+		return
+	}
+
+	chirpData, err := c.db.GetChirp(context.Background(), chirpID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error fetching the Chirp from the database: %v", err), "Chirp not found", http.StatusNotFound)
+		return
+	}
+	blocked, err := c.isBlockedBetween(uuid.NullUUID{UUID: user_id, Valid: true}, chirpData.UserID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error checking blocks: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if blocked {
+		respondWithError(writer, "A user tried to like a chirp hidden by a block.", "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	likeChirpParams := database.LikeChirpParams{
+		UserID:  user_id,
+		ChirpID: chirpID,
+	}
+	err = c.db.LikeChirp(context.Background(), likeChirpParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to save the like to the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) handlerLikeDELETE(writer http.ResponseWriter, request *http.Request) {
+	chirpID, err := uuid.Parse(request.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the chirp id: %v", err), "Invalid Chirp ID", http.StatusNotFound)
+		return
+	}
+
+	user_id, err := c.authenticate(request, scopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(writer, err)
+		return
+	}
+
+	unlikeChirpParams := database.UnlikeChirpParams{
+		UserID:  user_id,
+		ChirpID: chirpID,
+	}
+	err = c.db.UnlikeChirp(context.Background(), unlikeChirpParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to delete the like from the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) handlerUserLikesGET(writer http.ResponseWriter, request *http.Request) {
+	userID, err := uuid.Parse(request.PathValue("user_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the user id: %v", err), "Invalid User ID", http.StatusNotFound)
+		return
+	}
+
+	pageParams, err := parsePageParams(request.URL.Query())
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the pagination params: %v", err), "Invalid pagination params", http.StatusBadRequest)
+		return
+	}
+
+	var queryResult []chirpRow
+	cursorCreatedAt, cursorID := pageParams.cursorArgs()
+	if pageParams.queryDesc() {
+		queryResult, err = toChirpRows(c.db.ListLikedChirpsDesc(context.Background(), database.ListLikedChirpsDescParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			ViewerID:        c.optionalUserID(request),
+			Limit:           pageParams.queryLimit(),
+		}))
+	} else {
+		queryResult, err = toChirpRows(c.db.ListLikedChirpsAsc(context.Background(), database.ListLikedChirpsAscParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			ViewerID:        c.optionalUserID(request),
+			Limit:           pageParams.queryLimit(),
+		}))
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting liked chirps from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	c.respondWithChirpPage(writer, request, pageParams, queryResult)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/Mr-Rafael/chirpy/internal/database"
+)
+
+// loginFailureWindow is how long a failed attempt counts against a key. A
+// failure after a quiet window starts the count again.
+const loginFailureWindow = 1 * time.Hour
+
+// throttlePolicy says how hard to slow down a key that keeps failing. After
+// backoffAfter failures every further failure doubles the wait, starting at a
+// second; after lockoutAfter the key is locked out for lockout.
+type throttlePolicy struct {
+	backoffAfter int32
+	lockoutAfter int32
+	lockout      time.Duration
+}
+
+var (
+	accountThrottle = throttlePolicy{backoffAfter: 3, lockoutAfter: 10, lockout: 15 * time.Minute}
+	// Addresses are shared behind NATs and proxies, so they get more room
+	// before they are slowed down.
+	ipThrottle = throttlePolicy{backoffAfter: 20, lockoutAfter: 100, lockout: 15 * time.Minute}
+)
+
+type clearLockoutRequestParams struct {
+	Email string `json:"email"`
+	IP    string `json:"ip"`
+}
+
+func (p throttlePolicy) delay(failures int32) time.Duration {
+	if failures >= p.lockoutAfter {
+		return p.lockout
+	}
+	if failures < p.backoffAfter {
+		return 0
+	}
+	backoff := time.Duration(math.Pow(2, float64(failures-p.backoffAfter))) * time.Second
+	return min(backoff, p.lockout)
+}
+
+func accountThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginAttempt is one attempt counted against a throttle key, kept so the
+// attempt can be taken back if it turns out to be valid.
+type loginAttempt struct {
+	key         string
+	policy      throttlePolicy
+	failures    int32
+	lockedUntil sql.NullTime
+}
+
+// startLoginAttempt counts an attempt against the account and the address
+// before any password or code is checked, as if it were going to fail. The
+// count and the lock that goes with it are written in one transaction, so a
+// burst of parallel requests can't all slip in under the limit. If either key
+// is locked out it returns how long the caller has to wait, and nothing is
+// counted. It runs before any password is hashed, so a locked out attacker
+// can't burn CPU either.
+func (c *apiConfig) startLoginAttempt(email string, ip string) ([]loginAttempt, time.Duration, error) {
+	attempts := []loginAttempt{}
+	for _, throttle := range []struct {
+		policy throttlePolicy
+		key    string
+	}{
+		{accountThrottle, accountThrottleKey(email)},
+		{ipThrottle, ipThrottleKey(ip)},
+	} {
+		attempt, retryAfter, err := c.countLoginAttempt(throttle.policy, throttle.key)
+		if err != nil || retryAfter > 0 {
+			c.refundLoginAttempts(attempts)
+			return nil, retryAfter, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, 0, nil
+}
+
+// countLoginAttempt counts one attempt against a throttle key and sets the
+// lock policy calls for. Other endpoints that need throttling use it with
+// their own keys.
+func (c *apiConfig) countLoginAttempt(policy throttlePolicy, key string) (loginAttempt, time.Duration, error) {
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return loginAttempt{}, 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	// Postgres keeps microseconds; refundLoginAttempts matches the lock exactly.
+	now := time.Now().Truncate(time.Microsecond)
+	throttle, err := qtx.RecordLoginAttempt(context.Background(), database.RecordLoginAttemptParams{
+		Key:         key,
+		AttemptedAt: now,
+		WindowStart: now.Add(-loginFailureWindow),
+	})
+	if err != nil {
+		return loginAttempt{}, 0, fmt.Errorf("failed to count the login attempt for %v: %v", key, err)
+	}
+	if throttle.LockedUntil.Valid && throttle.LockedUntil.Time.After(now) {
+		return loginAttempt{}, throttle.LockedUntil.Time.Sub(now), nil
+	}
+
+	attempt := loginAttempt{key: key, policy: policy, failures: throttle.Failures}
+	delay := policy.delay(throttle.Failures)
+	if delay > 0 {
+		attempt.lockedUntil = sql.NullTime{Time: now.Add(delay), Valid: true}
+		err = qtx.LockLogin(context.Background(), database.LockLoginParams{
+			Key:         key,
+			LockedUntil: attempt.lockedUntil,
+		})
+		if err != nil {
+			return loginAttempt{}, 0, fmt.Errorf("failed to lock logins for %v: %v", key, err)
+		}
+	}
+	return attempt, 0, tx.Commit()
+}
+
+// failLoginAttempts logs the lockouts the failed attempts started. They were
+// already counted by startLoginAttempt.
+func failLoginAttempts(attempts []loginAttempt) {
+	for _, attempt := range attempts {
+		if attempt.failures == attempt.policy.lockoutAfter {
+			fmt.Printf("[Security]: login_lockout for %v after %v failed attempts\n", attempt.key, attempt.failures)
+		}
+	}
+}
+
+// refundLoginAttempts takes back attempts that turned out to be valid, along
+// with the lock each of them set. A later lock set by another attempt stays.
+func (c *apiConfig) refundLoginAttempts(attempts []loginAttempt) {
+	for _, attempt := range attempts {
+		err := c.db.RefundLoginAttempt(context.Background(), database.RefundLoginAttemptParams{
+			Key:         attempt.key,
+			LockedUntil: attempt.lockedUntil,
+		})
+		if err != nil {
+			fmt.Printf("[Error]: Failed to refund a login attempt for %v: %v\n", attempt.key, err)
+		}
+	}
+}
+
+// clearLoginFailures forgets an account's failures once it logs in. The
+// address keeps its count, or one valid account would let an attacker reset
+// the limit for every other account they try from there.
+func (c *apiConfig) clearLoginFailures(email string) {
+	_, err := c.db.ClearLoginThrottle(context.Background(), accountThrottleKey(email))
+	if err != nil {
+		fmt.Printf("[Error]: Failed to clear failed logins for %v: %v\n", email, err)
+	}
+}
+
+func respondWithTooManyAttempts(writer http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	writer.Header().Set("Retry-After", fmt.Sprint(seconds))
+	respondWithError(writer, fmt.Sprintf("Login attempt while throttled for another %v seconds.", seconds), "Too many failed login attempts. Try again later", http.StatusTooManyRequests)
+}
+
+func respondWithTooManyRequests(writer http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	writer.Header().Set("Retry-After", fmt.Sprint(seconds))
+	respondWithError(writer, fmt.Sprintf("Request while throttled for another %v seconds.", seconds), "Too many requests. Try again later", http.StatusTooManyRequests)
+}
+
+// handlerClearLockout lets an admin unlock an account or address early. It is
+// authenticated with the ADMIN_API_KEY and disabled when that isn't set.
+func (c *apiConfig) handlerClearLockout(writer http.ResponseWriter, request *http.Request) {
+	headerKey, err := auth.GetAPIKey(request.Header)
+	if err != nil || c.adminKey == "" || subtle.ConstantTimeCompare([]byte(headerKey), []byte(c.adminKey)) != 1 {
+		respondWithError(writer, "The lockout endpoint was hit without a valid admin key.", "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	reqParams := clearLockoutRequestParams{}
+	err = decoder.Decode(&reqParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to decode the request: %v", err), "Something went wrong", http.StatusBadRequest)
+		return
+	}
+	var keys []string
+	if reqParams.Email != "" {
+		keys = append(keys, accountThrottleKey(reqParams.Email))
+	}
+	if reqParams.IP != "" {
+		keys = append(keys, ipThrottleKey(reqParams.IP))
+	}
+	if len(keys) == 0 {
+		respondWithError(writer, "Lockout clear requested without an email or IP.", "Missing param: email or ip", http.StatusBadRequest)
+		return
+	}
+
+	var cleared int64
+	for _, key := range keys {
+		rows, err := c.db.ClearLoginThrottle(context.Background(), key)
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("Failed to clear the lockout for %v: %v", key, err), "Something went wrong.", http.StatusInternalServerError)
+			return
+		}
+		cleared += rows
+	}
+	if cleared == 0 {
+		respondWithError(writer, fmt.Sprintf("No lockout found for %v.", keys), "No lockout found", http.StatusNotFound)
+		return
+	}
+	fmt.Printf("[Security]: login_lockout_cleared for %v\n", strings.Join(keys, ", "))
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -6,9 +6,13 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync/atomic"
+	"time"
 
+	"github.com/Mr-Rafael/chirpy/internal/auth"
 	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/Mr-Rafael/chirpy/internal/mailer"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -16,42 +20,156 @@ import (
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	db             *database.Queries
+	dbConn         *sql.DB
 	platform       string
-	secret         string
+	jwtKeys        *auth.KeySet
+	jwtAudience    string
+	jwtValidator   auth.ValidatorConfig
 	polkaKey       string
+	adminKey       string
+	publicURL      string
+	mailer         mailer.Mailer
+
+	accountDeletionGrace time.Duration
+	exportDir            string
 }
 
+// defaultKeyRotation is how long a key signs tokens before it is replaced.
+// Retired keys keep verifying for another rotation period, which comfortably
+// outlives the tokens they signed and any JWKS cached by other services.
+const defaultKeyRotation = 30 * 24 * time.Hour
+
+// defaultJWTLeeway is the clock skew tolerated between the host that minted a
+// token and the one checking it.
+const defaultJWTLeeway = 30 * time.Second
+
 func main() {
 	port := ":8080"
 	mux := http.NewServeMux()
 	godotenv.Load()
 	dbURL := os.Getenv("DB_URL")
+	keyDir := envOrDefault("JWT_KEY_DIR", "./keys")
 
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		log.Fatalf("error opening connection to the database: %v", err)
 	}
+	keyRotation := defaultKeyRotation
+	if rotation := os.Getenv("JWT_KEY_ROTATION"); rotation != "" {
+		keyRotation, err = time.ParseDuration(rotation)
+		if err != nil {
+			log.Fatalf("invalid JWT_KEY_ROTATION: %v", err)
+		}
+	}
+	jwtLeeway := defaultJWTLeeway
+	if leeway := os.Getenv("JWT_LEEWAY"); leeway != "" {
+		jwtLeeway, err = time.ParseDuration(leeway)
+		if err != nil {
+			log.Fatalf("invalid JWT_LEEWAY: %v", err)
+		}
+	}
+	accountDeletionGrace := defaultAccountDeletionGrace
+	if grace := os.Getenv("ACCOUNT_DELETION_GRACE"); grace != "" {
+		accountDeletionGrace, err = time.ParseDuration(grace)
+		if err != nil {
+			log.Fatalf("invalid ACCOUNT_DELETION_GRACE: %v", err)
+		}
+	}
 
 	var config apiConfig
 	config.fileserverHits.Store(0)
 	config.db = database.New(db)
+	config.dbConn = db
 	config.platform = os.Getenv("PLATFORM")
-	config.secret = os.Getenv("SECRET")
+	config.jwtKeys, err = auth.LoadKeySet(keyDir)
+	if err != nil {
+		log.Fatalf("error loading the JWT signing keys: %v", err)
+	}
+	go config.rotateSigningKeys(keyRotation)
+	config.jwtAudience = envOrDefault("JWT_AUDIENCE", "chirpy-api")
+	config.jwtValidator = auth.ValidatorConfig{
+		Issuer:         envOrDefault("JWT_ISSUER", "chirpy"),
+		Audiences:      []string{config.jwtAudience},
+		Leeway:         jwtLeeway,
+		RequiredClaims: []string{"iss", "sub", "aud", "exp", "iat"},
+		MaxTokenAge:    accessTokenLifetime,
+	}
 	config.polkaKey = os.Getenv("POLKA_KEY")
+	config.adminKey = os.Getenv("ADMIN_API_KEY")
+	// PUBLIC_URL is the address users reach this server at, for links in emails.
+	config.publicURL = strings.TrimSuffix(envOrDefault("PUBLIC_URL", "http://localhost:8080"), "/")
+	config.mailer = newMailer()
+	config.accountDeletionGrace = accountDeletionGrace
+	go config.purgeDeletedAccounts()
+	config.exportDir = envOrDefault("DATA_EXPORT_DIR", "./exports")
+	err = config.failInterruptedDataExports()
+	if err != nil {
+		log.Fatalf("error cleaning up interrupted data exports: %v", err)
+	}
+	go config.expireDataExports()
 
 	mux.Handle("/app/", config.middlewareMetricsInc(http.StripPrefix("/app/", http.FileServer(http.Dir("./files")))))
 	mux.HandleFunc("GET /api/healthz", handlerHealthZ)
+	mux.HandleFunc("GET /.well-known/jwks.json", config.handlerJWKS)
 	mux.HandleFunc("GET /admin/metrics", config.handlerMetrics)
 	mux.HandleFunc("POST /admin/reset", config.handlerReset)
+	mux.HandleFunc("POST /admin/lockouts/clear", config.handlerClearLockout)
 	mux.HandleFunc("POST /api/users", config.handlerUsers)
 	mux.HandleFunc("POST /api/chirps", config.handlerChirpsPOST)
+	mux.HandleFunc("POST /api/chirps/import", config.handlerChirpsImport)
 	mux.HandleFunc("GET /api/chirps", config.handlerChirpsGET)
 	mux.HandleFunc("GET /api/chirps/{chirp_id}", config.handlerChirpsGETID)
+	mux.HandleFunc("PUT /api/chirps/{chirp_id}", config.handlerChirpsPUT)
+	mux.HandleFunc("PATCH /api/chirps/{chirp_id}", config.handlerChirpsPUT)
+	mux.HandleFunc("GET /api/chirps/{chirp_id}/history", config.handlerChirpsHistory)
+	mux.HandleFunc("GET /api/chirps/{chirp_id}/replies", config.handlerChirpsReplies)
+	mux.HandleFunc("GET /api/chirps/{chirp_id}/thread", config.handlerChirpsThread)
 	mux.HandleFunc("DELETE /api/chirps/{chirp_id}", config.handlerChirpsDELETE)
+	mux.HandleFunc("POST /api/chirps/{chirp_id}/rechirp", config.handlerRechirpPOST)
+	mux.HandleFunc("POST /api/chirps/{chirp_id}/like", config.handlerLikePOST)
+	mux.HandleFunc("DELETE /api/chirps/{chirp_id}/like", config.handlerLikeDELETE)
 	mux.HandleFunc("POST /api/login", config.handlerLogin)
+	mux.HandleFunc("POST /api/login/mfa", config.handlerLoginMFA)
+	mux.HandleFunc("POST /api/password-reset", config.handlerPasswordResetRequest)
+	mux.HandleFunc("POST /api/password-reset/confirm", config.handlerPasswordResetConfirm)
+	mux.HandleFunc("POST /api/mfa/totp/enroll", config.handlerTOTPEnroll)
+	mux.HandleFunc("POST /api/mfa/totp/confirm", config.handlerTOTPConfirm)
+	mux.HandleFunc("DELETE /api/mfa/totp", config.handlerTOTPDELETE)
 	mux.HandleFunc("POST /api/refresh", config.handlerRefresh)
 	mux.HandleFunc("POST /api/revoke", config.handlerRevoke)
+	mux.HandleFunc("GET /api/sessions", config.handlerSessionsGET)
+	mux.HandleFunc("DELETE /api/sessions/{session_id}", config.handlerSessionDELETE)
+	mux.HandleFunc("POST /api/logout-all", config.handlerLogoutAll)
+	mux.HandleFunc("POST /api/tokens", config.handlerTokensPOST)
+	mux.HandleFunc("GET /api/tokens", config.handlerTokensGET)
+	mux.HandleFunc("DELETE /api/tokens/{token_id}", config.handlerTokenDELETE)
 	mux.HandleFunc("PUT /api/users", config.handlerUsersPUT)
+	mux.HandleFunc("PATCH /api/users/me", config.handlerUsersPATCH)
+	mux.HandleFunc("DELETE /api/users/me", config.handlerUsersDELETE)
+	mux.HandleFunc("POST /api/users/me/export", config.handlerDataExportPOST)
+	mux.HandleFunc("GET /api/users/me/export/{export_id}", config.handlerDataExportGET)
+	mux.HandleFunc("GET /api/users/me/export/{export_id}/download", config.handlerDataExportDownload)
+	mux.HandleFunc("GET /api/verify-email", config.handlerVerifyEmail)
+	mux.HandleFunc("POST /api/verify-email/resend", config.handlerResendEmailVerification)
+	mux.HandleFunc("GET /api/users/{id_or_username}", config.handlerUserProfileGET)
+	mux.HandleFunc("POST /api/users/{user_id}/follow", config.handlerFollowPOST)
+	mux.HandleFunc("DELETE /api/users/{user_id}/follow", config.handlerFollowDELETE)
+	mux.HandleFunc("POST /api/users/{user_id}/block", config.handlerBlockPOST)
+	mux.HandleFunc("DELETE /api/users/{user_id}/block", config.handlerBlockDELETE)
+	mux.HandleFunc("POST /api/users/{user_id}/mute", config.handlerMutePOST)
+	mux.HandleFunc("DELETE /api/users/{user_id}/mute", config.handlerMuteDELETE)
+	mux.HandleFunc("GET /api/blocks", config.handlerBlocksGET)
+	mux.HandleFunc("GET /api/mutes", config.handlerMutesGET)
+	mux.HandleFunc("GET /api/users/{user_id}/followers", config.handlerFollowersGET)
+	mux.HandleFunc("GET /api/users/{user_id}/following", config.handlerFollowingGET)
+	mux.HandleFunc("GET /api/users/{user_id}/likes", config.handlerUserLikesGET)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", config.handlerHashtagChirpsGET)
+	mux.HandleFunc("GET /api/search/chirps", config.handlerSearchChirps)
+	mux.HandleFunc("GET /api/timeline", config.handlerTimelineGET)
+	mux.HandleFunc("GET /api/notifications", config.handlerNotificationsGET)
+	mux.HandleFunc("GET /api/notifications/unread-count", config.handlerNotificationsUnreadCount)
+	mux.HandleFunc("POST /api/notifications/{notification_id}/read", config.handlerNotificationRead)
+	mux.HandleFunc("POST /api/notifications/read-all", config.handlerNotificationsReadAll)
 	mux.HandleFunc("POST /api/polka/webhooks", config.handlerPolkaWebhook)
 
 	server := &http.Server{
@@ -65,9 +183,59 @@ func main() {
 	}
 }
 
+// newMailer sends mail over SMTP when MAIL_SMTP_ADDR is set. Otherwise mail
+// is written to MAIL_DIR, or printed, which is what dev setups want.
+func newMailer() mailer.Mailer {
+	from := envOrDefault("MAIL_FROM", "Chirpy <no-reply@chirpy.local>")
+	if addr := os.Getenv("MAIL_SMTP_ADDR"); addr != "" {
+		return mailer.SMTPMailer{
+			Addr:     addr,
+			From:     from,
+			Username: os.Getenv("MAIL_SMTP_USERNAME"),
+			Password: os.Getenv("MAIL_SMTP_PASSWORD"),
+		}
+	}
+	return &mailer.FileMailer{Dir: os.Getenv("MAIL_DIR"), From: from}
+}
+
+func envOrDefault(key string, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cfg.fileserverHits.Add(1)
 		next.ServeHTTP(w, r)
 	})
 }
+
+// rotateSigningKeys periodically picks up keys written by other instances,
+// replaces the signing key once it is older than rotation and prunes keys
+// nothing should still be verifying with.
+func (cfg *apiConfig) rotateSigningKeys(rotation time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		err := cfg.jwtKeys.Reload()
+		if err != nil {
+			fmt.Printf("[Error]: Failed to reload the JWT signing keys: %v\n", err)
+			continue
+		}
+		if cfg.jwtKeys.SigningKeyAge() >= rotation {
+			kid, err := cfg.jwtKeys.Rotate()
+			if err != nil {
+				fmt.Printf("[Error]: Failed to rotate the JWT signing key: %v\n", err)
+				continue
+			}
+			fmt.Printf("Rotated the JWT signing key to %v\n", kid)
+		}
+		err = cfg.jwtKeys.Prune(2 * rotation)
+		if err != nil {
+			fmt.Printf("[Error]: Failed to prune old JWT signing keys: %v\n", err)
+		}
+	}
+}
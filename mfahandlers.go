@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// mfaChallengeAudience keeps challenge tokens from being accepted as
+	// access tokens, and access tokens from skipping the second step.
+	mfaChallengeAudience = "chirpy-mfa"
+	mfaChallengeLifetime = 5 * time.Minute
+)
+
+type mfaCodeRequestParams struct {
+	Code string `json:"code"`
+}
+
+type mfaLoginRequestParams struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type mfaChallengeResponseParams struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type totpEnrollResponseParams struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type totpConfirmResponseParams struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (c *apiConfig) respondWithMFAChallenge(writer http.ResponseWriter, userID uuid.UUID) {
+	mfaToken, err := auth.MakeJWT(userID, c.jwtKeys, c.jwtValidator.Issuer, mfaChallengeAudience, mfaChallengeLifetime)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to generate MFA challenge for user: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	responseParams := mfaChallengeResponseParams{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+	}
+	respondWithJSON(writer, responseParams, http.StatusOK)
+}
+
+// handlerLoginMFA is the second login step for users with TOTP enabled. It
+// exchanges the challenge from /api/login and a TOTP or recovery code for the
+// usual access and refresh tokens.
+func (c *apiConfig) handlerLoginMFA(writer http.ResponseWriter, request *http.Request) {
+	decoder := json.NewDecoder(request.Body)
+	reqParams := mfaLoginRequestParams{}
+	err := decoder.Decode(&reqParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to decode the request: %v", err), "Something went wrong", http.StatusBadRequest)
+		return
+	}
+	if len(reqParams.Code) <= 0 {
+		respondWithError(writer, "The MFA code came empty.", "Missing param: code", http.StatusBadRequest)
+		return
+	}
+
+	challengeValidator := c.jwtValidator
+	challengeValidator.Audiences = []string{mfaChallengeAudience}
+	challengeValidator.MaxTokenAge = mfaChallengeLifetime
+	userID, err := auth.ValidateJWT(reqParams.MFAToken, c.jwtKeys, challengeValidator)
+	if err != nil || userID == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating MFA challenge: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userData, err := c.db.GetUserByID(context.Background(), userID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get user data: %v", err), "User not found", http.StatusNotFound)
+		return
+	}
+	clientAddress := clientIP(request)
+	attempts, retryAfter, err := c.startLoginAttempt(userData.Email, clientAddress)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to check login throttling: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if retryAfter > 0 {
+		respondWithTooManyAttempts(writer, retryAfter)
+		return
+	}
+
+	totpData, err := c.db.GetUserTOTP(context.Background(), userID)
+	if err != nil || !totpData.ConfirmedAt.Valid {
+		c.refundLoginAttempts(attempts)
+		respondWithError(writer, fmt.Sprintf("MFA challenge for user %v without TOTP enabled: %v", userID, err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	verified, err := c.verifySecondFactor(totpData, reqParams.Code)
+	if err != nil {
+		c.refundLoginAttempts(attempts)
+		respondWithError(writer, fmt.Sprintf("Failed to verify the MFA code: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if !verified {
+		failLoginAttempts(attempts)
+		c.logSecurityEvent(userID, "mfa_failed", "An invalid TOTP or recovery code was presented at login.")
+		respondWithError(writer, "Login attempt with an invalid MFA code.", "Invalid code", http.StatusUnauthorized)
+		return
+	}
+	c.refundLoginAttempts(attempts)
+
+	c.respondWithLogin(writer, request, userData)
+}
+
+// handlerTOTPEnroll starts TOTP enrolment with a fresh secret. It only takes
+// effect once confirmed with a code, so a half-finished enrolment never locks
+// the user out.
+func (c *apiConfig) handlerTOTPEnroll(writer http.ResponseWriter, request *http.Request) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userData, err := c.db.GetUserByID(context.Background(), jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get user data: %v", err), "User not found", http.StatusNotFound)
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to generate TOTP secret: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	createParams := database.CreatePendingTOTPParams{
+		UserID: jwt_user_id,
+		Secret: secret,
+	}
+	created, err := c.db.CreatePendingTOTP(context.Background(), createParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to save the TOTP secret: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if created == 0 {
+		respondWithError(writer, "TOTP enrolment requested with TOTP already enabled.", "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	responseParams := totpEnrollResponseParams{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(secret, c.jwtValidator.Issuer, userData.Email),
+	}
+	respondWithJSON(writer, responseParams, http.StatusOK)
+}
+
+func (c *apiConfig) handlerTOTPConfirm(writer http.ResponseWriter, request *http.Request) {
+	decoder := json.NewDecoder(request.Body)
+	reqParams := mfaCodeRequestParams{}
+	err := decoder.Decode(&reqParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to decode the request: %v", err), "Something went wrong", http.StatusBadRequest)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	totpData, err := c.db.GetUserTOTP(context.Background(), jwt_user_id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(writer, "TOTP confirmation without a pending enrolment.", "Start TOTP enrolment first", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get the user's TOTP settings: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if totpData.ConfirmedAt.Valid {
+		respondWithError(writer, "TOTP confirmation with TOTP already enabled.", "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	step, ok := auth.ValidateTOTP(totpData.Secret, reqParams.Code, time.Now())
+	if !ok {
+		respondWithError(writer, "TOTP confirmation with an invalid code.", "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, err := c.enableTOTP(jwt_user_id, step)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to enable TOTP: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	c.logSecurityEvent(jwt_user_id, "totp_enabled", "Two-factor authentication was enabled.")
+	respondWithJSON(writer, totpConfirmResponseParams{RecoveryCodes: recoveryCodes}, http.StatusOK)
+}
+
+func (c *apiConfig) handlerTOTPDELETE(writer http.ResponseWriter, request *http.Request) {
+	decoder := json.NewDecoder(request.Body)
+	reqParams := mfaCodeRequestParams{}
+	err := decoder.Decode(&reqParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to decode the request: %v", err), "Something went wrong", http.StatusBadRequest)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	totpData, err := c.db.GetUserTOTP(context.Background(), jwt_user_id)
+	if err != nil || !totpData.ConfirmedAt.Valid {
+		respondWithError(writer, fmt.Sprintf("TOTP removal without TOTP enabled: %v", err), "Two-factor authentication is not enabled", http.StatusNotFound)
+		return
+	}
+	verified, err := c.verifySecondFactor(totpData, reqParams.Code)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to verify the MFA code: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if !verified {
+		respondWithError(writer, "TOTP removal with an invalid code.", "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	err = c.disableTOTP(jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to disable TOTP: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	c.logSecurityEvent(jwt_user_id, "totp_disabled", "Two-factor authentication was disabled.")
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery
+// code. Each TOTP time step and each recovery code only works once.
+func (c *apiConfig) verifySecondFactor(totpData database.UserTotp, code string) (bool, error) {
+	step, ok := auth.ValidateTOTP(totpData.Secret, code, time.Now())
+	if ok {
+		used, err := c.db.UseTOTPStep(context.Background(), database.UseTOTPStepParams{
+			UserID:   totpData.UserID,
+			LastStep: step,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to record the TOTP step: %v", err)
+		}
+		return used > 0, nil
+	}
+
+	used, err := c.db.UseRecoveryCode(context.Background(), database.UseRecoveryCodeParams{
+		UserID:   totpData.UserID,
+		CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to use the recovery code: %v", err)
+	}
+	if used > 0 {
+		c.logSecurityEvent(totpData.UserID, "recovery_code_used", "A recovery code was used in place of a TOTP code.")
+	}
+	return used > 0, nil
+}
+
+// enableTOTP confirms a pending enrolment and replaces the user's recovery
+// codes, returning the new ones so they can be shown once.
+func (c *apiConfig) enableTOTP(userID uuid.UUID, step int64) ([]string, error) {
+	recoveryCodes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	codeHashes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		codeHashes = append(codeHashes, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+	}
+
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	confirmed, err := qtx.ConfirmTOTP(context.Background(), database.ConfirmTOTPParams{
+		UserID:   userID,
+		LastStep: step,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm the TOTP secret: %v", err)
+	}
+	if confirmed == 0 {
+		return nil, fmt.Errorf("no pending TOTP enrolment for user %v", userID)
+	}
+	err = qtx.DeleteRecoveryCodes(context.Background(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete old recovery codes: %v", err)
+	}
+	err = qtx.CreateRecoveryCodes(context.Background(), database.CreateRecoveryCodesParams{
+		UserID:     userID,
+		CodeHashes: codeHashes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %v", err)
+	}
+	return recoveryCodes, tx.Commit()
+}
+
+func (c *apiConfig) disableTOTP(userID uuid.UUID) error {
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	err = qtx.DeleteUserTOTP(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete the TOTP secret: %v", err)
+	}
+	err = qtx.DeleteRecoveryCodes(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %v", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([A-Za-z0-9_]{3,30})\b`)
+
+type notificationResponseParams struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt string     `json:"created_at"`
+	Kind      string     `json:"kind"`
+	ActorID   uuid.UUID  `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	Read      bool       `json:"read"`
+}
+
+type unreadCountResponseParams struct {
+	Unread int64 `json:"unread"`
+}
+
+// extractMentions returns the distinct, lowercased usernames mentioned in a
+// chirp body.
+func extractMentions(text string) []string {
+	usernames := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		username := strings.ToLower(match[1])
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+// saveMentions links a chirp to the users it mentions and notifies them.
+// Mentions that don't resolve to a user are ignored.
+func saveMentions(qtx *database.Queries, chirp chirpRow) error {
+	err := qtx.CreateChirpMentions(context.Background(), database.CreateChirpMentionsParams{
+		ChirpID:   chirp.ID,
+		Usernames: extractMentions(chirp.Body),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save mentions: %v", err)
+	}
+	err = qtx.CreateMentionNotifications(context.Background(), chirp.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create mention notifications: %v", err)
+	}
+	return nil
+}
+
+func (c *apiConfig) handlerNotificationsGET(writer http.ResponseWriter, request *http.Request) {
+	user_id, err := c.authenticate(request, scopeChirpsRead)
+	if err != nil {
+		respondWithAuthError(writer, err)
+		return
+	}
+
+	pageParams, err := parsePageParams(request.URL.Query())
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the pagination params: %v", err), "Invalid pagination params", http.StatusBadRequest)
+		return
+	}
+	// Notifications read newest first unless asked otherwise.
+	pageParams.Desc = request.URL.Query().Get("sort") != "asc"
+	unreadOnly := request.URL.Query().Get("unread") == "true"
+
+	var queryResult []database.Notification
+	cursorCreatedAt, cursorID := pageParams.cursorArgs()
+	if pageParams.queryDesc() {
+		queryResult, err = c.db.ListNotificationsDesc(context.Background(), database.ListNotificationsDescParams{
+			UserID:          user_id,
+			UnreadOnly:      unreadOnly,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           pageParams.queryLimit(),
+		})
+	} else {
+		queryResult, err = c.db.ListNotificationsAsc(context.Background(), database.ListNotificationsAscParams{
+			UserID:          user_id,
+			UnreadOnly:      unreadOnly,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           pageParams.queryLimit(),
+		})
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error getting notifications from database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	queryResult, nextCursor, prevCursor := paginate(pageParams, queryResult, func(notification database.Notification) pageCursor {
+		return pageCursor{CreatedAt: notification.CreatedAt, ID: notification.ID}
+	})
+	setPageLinks(writer, request, pageParams, nextCursor, prevCursor)
+
+	responseData := []notificationResponseParams{}
+	for _, notification := range queryResult {
+		notificationData := notificationResponseParams{
+			ID:        notification.ID,
+			CreatedAt: notification.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			Kind:      notification.Kind,
+			ActorID:   notification.ActorID,
+			Read:      notification.ReadAt.Valid,
+		}
+		if notification.ChirpID.Valid {
+			notificationData.ChirpID = &notification.ChirpID.UUID
+		}
+		responseData = append(responseData, notificationData)
+	}
+	respondWithJSON(writer, responseData, http.StatusOK)
+}
+
+func (c *apiConfig) handlerNotificationsUnreadCount(writer http.ResponseWriter, request *http.Request) {
+	user_id, err := c.authenticate(request, scopeChirpsRead)
+	if err != nil {
+		respondWithAuthError(writer, err)
+		return
+	}
+
+	unread, err := c.db.CountUnreadNotifications(context.Background(), user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error counting unread notifications: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(writer, unreadCountResponseParams{Unread: unread}, http.StatusOK)
+}
+
+func (c *apiConfig) handlerNotificationRead(writer http.ResponseWriter, request *http.Request) {
+	notificationID, err := uuid.Parse(request.PathValue("notification_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the notification id: %v", err), "Invalid Notification ID", http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	markParams := database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: jwt_user_id,
+	}
+	updated, err := c.db.MarkNotificationRead(context.Background(), markParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to mark the notification as read: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if updated == 0 {
+		respondWithError(writer, "The notification doesn't exist or belongs to another user.", "Notification not found", http.StatusNotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) handlerNotificationsReadAll(writer http.ResponseWriter, request *http.Request) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err = c.db.MarkAllNotificationsRead(context.Background(), jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to mark the notifications as read: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
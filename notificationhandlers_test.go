@@ -0,0 +1,35 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "none", text: "just a chirp", want: []string{}},
+		{name: "order of first appearance", text: "hi @zoe and @Adam", want: []string{"zoe", "adam"}},
+		{name: "duplicates in any case", text: "@Bob @bob @BOB", want: []string{"bob"}},
+		{name: "punctuation ends a mention", text: "thanks @alice, and @carol!", want: []string{"alice", "carol"}},
+		{name: "underscores and digits", text: "@dev_42", want: []string{"dev_42"}},
+		{name: "email addresses", text: "write to bob@example.com", want: []string{}},
+		{name: "double at", text: "@@bob", want: []string{}},
+		{name: "too short", text: "@ab", want: []string{}},
+		{name: "longest allowed", text: "@abcdefghijabcdefghijabcdefghij", want: []string{"abcdefghijabcdefghijabcdefghij"}},
+		{name: "too long", text: "@abcdefghijabcdefghijabcdefghijk", want: []string{}},
+		{name: "after an opening bracket", text: "(@alice)", want: []string{"alice"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := extractMentions(test.text)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Expected %q, got %q.", test.want, got)
+			}
+		})
+	}
+}
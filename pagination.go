@@ -0,0 +1,158 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// pageCursor points at the row a page starts after. Backward cursors walk
+// towards the start of the listing instead of away from it.
+type pageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	Rank      float32   `json:"r,omitempty"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+type pageQuery struct {
+	Limit  int32
+	Desc   bool
+	Cursor *pageCursor
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded string) (pageCursor, error) {
+	cursor := pageCursor{}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, fmt.Errorf("failed to decode cursor: %v", err)
+	}
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return cursor, fmt.Errorf("failed to parse cursor: %v", err)
+	}
+	if cursor.ID == uuid.Nil || cursor.CreatedAt.IsZero() {
+		return cursor, fmt.Errorf("cursor is missing fields")
+	}
+	return cursor, nil
+}
+
+func parsePageParams(query url.Values) (pageQuery, error) {
+	params := pageQuery{
+		Limit: defaultPageLimit,
+		Desc:  query.Get("sort") == "desc",
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		parsedLimit, err := strconv.Atoi(limit)
+		if err != nil || parsedLimit <= 0 {
+			return params, fmt.Errorf("invalid limit: %v", limit)
+		}
+		params.Limit = int32(min(parsedLimit, maxPageLimit))
+	}
+
+	if encoded := query.Get("cursor"); encoded != "" {
+		cursor, err := decodeCursor(encoded)
+		if err != nil {
+			return params, err
+		}
+		params.Cursor = &cursor
+	}
+	return params, nil
+}
+
+// queryDesc reports the order the rows have to be fetched in. Walking a
+// listing backwards means reading it in the opposite direction.
+func (p pageQuery) queryDesc() bool {
+	if p.Cursor != nil && p.Cursor.Backward {
+		return !p.Desc
+	}
+	return p.Desc
+}
+
+// queryLimit fetches one extra row so we can tell whether another page exists.
+func (p pageQuery) queryLimit() int32 {
+	return p.Limit + 1
+}
+
+func (p pageQuery) cursorArgs() (sql.NullTime, uuid.NullUUID) {
+	if p.Cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: p.Cursor.CreatedAt, Valid: true}, uuid.NullUUID{UUID: p.Cursor.ID, Valid: true}
+}
+
+// paginate trims the extra row fetched by queryLimit, restores the requested
+// order and works out the cursors for the neighbouring pages.
+func paginate[T any](params pageQuery, rows []T, cursorFor func(T) pageCursor) ([]T, *pageCursor, *pageCursor) {
+	backward := params.Cursor != nil && params.Cursor.Backward
+	hasMore := len(rows) > int(params.Limit)
+	if hasMore {
+		rows = rows[:params.Limit]
+	}
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, nil, nil
+	}
+
+	var next, prev *pageCursor
+	if hasMore || backward {
+		last := cursorFor(rows[len(rows)-1])
+		next = &last
+	}
+	if (backward && hasMore) || (!backward && params.Cursor != nil) {
+		first := cursorFor(rows[0])
+		first.Backward = true
+		prev = &first
+	}
+	return rows, next, prev
+}
+
+func paginateChirps(params pageQuery, rows []chirpRow) ([]chirpRow, *pageCursor, *pageCursor) {
+	return paginate(params, rows, func(chirp chirpRow) pageCursor {
+		return pageCursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+	})
+}
+
+func setPageLinks(writer http.ResponseWriter, request *http.Request, params pageQuery, next *pageCursor, prev *pageCursor) {
+	var links []string
+	for _, page := range []struct {
+		rel    string
+		cursor *pageCursor
+	}{{"next", next}, {"prev", prev}} {
+		if page.cursor == nil {
+			continue
+		}
+		pageURL := *request.URL
+		query := pageURL.Query()
+		query.Set("cursor", encodeCursor(*page.cursor))
+		query.Set("limit", strconv.Itoa(int(params.Limit)))
+		pageURL.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf("<%v>; rel=\"%v\"", pageURL.RequestURI(), page.rel))
+	}
+	if len(links) > 0 {
+		writer.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
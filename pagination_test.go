@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor pageCursor
+	}{
+		{
+			name:   "forward",
+			cursor: pageCursor{CreatedAt: time.Date(2025, 6, 1, 12, 0, 0, 123456000, time.UTC), ID: uuid.New()},
+		},
+		{
+			name:   "backward with a rank",
+			cursor: pageCursor{CreatedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), ID: uuid.New(), Rank: 0.25, Backward: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, err := decodeCursor(encodeCursor(test.cursor))
+			if err != nil {
+				t.Fatalf("Failed to decode the cursor: %v", err)
+			}
+			if !decoded.CreatedAt.Equal(test.cursor.CreatedAt) || decoded.ID != test.cursor.ID || decoded.Rank != test.cursor.Rank || decoded.Backward != test.cursor.Backward {
+				t.Errorf("Expected %+v, got %+v.", test.cursor, decoded)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "not base64", encoded: "not base64!"},
+		{name: "not JSON", encoded: base64.RawURLEncoding.EncodeToString([]byte("hello"))},
+		{name: "missing id", encoded: base64.RawURLEncoding.EncodeToString([]byte(`{"t":"2025-06-01T12:00:00Z"}`))},
+		{name: "missing time", encoded: base64.RawURLEncoding.EncodeToString([]byte(`{"id":"` + uuid.NewString() + `"}`))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decodeCursor(test.encoded)
+			if err == nil {
+				t.Errorf("Expected %q to be rejected.", test.encoded)
+			}
+		})
+	}
+}
+
+func TestParsePageParams(t *testing.T) {
+	cursor := pageCursor{CreatedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), ID: uuid.New()}
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantErr    bool
+		wantLimit  int32
+		wantDesc   bool
+		wantCursor bool
+	}{
+		{name: "defaults", query: url.Values{}, wantLimit: defaultPageLimit},
+		{name: "limit and sort", query: url.Values{"limit": {"10"}, "sort": {"desc"}}, wantLimit: 10, wantDesc: true},
+		{name: "limit is capped", query: url.Values{"limit": {"1000"}}, wantLimit: maxPageLimit},
+		{name: "cursor", query: url.Values{"cursor": {encodeCursor(cursor)}}, wantLimit: defaultPageLimit, wantCursor: true},
+		{name: "zero limit", query: url.Values{"limit": {"0"}}, wantErr: true},
+		{name: "negative limit", query: url.Values{"limit": {"-5"}}, wantErr: true},
+		{name: "limit not a number", query: url.Values{"limit": {"ten"}}, wantErr: true},
+		{name: "bad cursor", query: url.Values{"cursor": {"garbage"}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params, err := parsePageParams(test.query)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v.", params)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if params.Limit != test.wantLimit || params.Desc != test.wantDesc || (params.Cursor != nil) != test.wantCursor {
+				t.Errorf("Unexpected params %+v.", params)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cursorFor := func(minute int) pageCursor {
+		return pageCursor{CreatedAt: start.Add(time.Duration(minute) * time.Minute), ID: uuid.Nil}
+	}
+	backwardFrom := func(minute int) *pageCursor {
+		cursor := cursorFor(minute)
+		cursor.Backward = true
+		return &cursor
+	}
+	forwardFrom := func(minute int) *pageCursor {
+		cursor := cursorFor(minute)
+		return &cursor
+	}
+
+	tests := []struct {
+		name      string
+		params    pageQuery
+		rows      []int
+		wantRows  []int
+		wantNext  *pageCursor
+		wantPrev  *pageCursor
+		wantQDesc bool
+	}{
+		{
+			name:     "first page with more after it",
+			params:   pageQuery{Limit: 2},
+			rows:     []int{1, 2, 3},
+			wantRows: []int{1, 2},
+			wantNext: forwardFrom(2),
+		},
+		{
+			name:     "only page",
+			params:   pageQuery{Limit: 2},
+			rows:     []int{1, 2},
+			wantRows: []int{1, 2},
+		},
+		{
+			name:     "middle page",
+			params:   pageQuery{Limit: 2, Cursor: forwardFrom(2)},
+			rows:     []int{3, 4, 5},
+			wantRows: []int{3, 4},
+			wantNext: forwardFrom(4),
+			wantPrev: backwardFrom(3),
+		},
+		{
+			name:     "last page",
+			params:   pageQuery{Limit: 2, Cursor: forwardFrom(4)},
+			rows:     []int{5},
+			wantRows: []int{5},
+			wantPrev: backwardFrom(5),
+		},
+		{
+			name:      "walking back restores the order",
+			params:    pageQuery{Limit: 2, Cursor: backwardFrom(5)},
+			rows:      []int{4, 3, 2},
+			wantRows:  []int{3, 4},
+			wantNext:  forwardFrom(4),
+			wantPrev:  backwardFrom(3),
+			wantQDesc: true,
+		},
+		{
+			name:      "walking back to the first page",
+			params:    pageQuery{Limit: 2, Cursor: backwardFrom(3)},
+			rows:      []int{2, 1},
+			wantRows:  []int{1, 2},
+			wantNext:  forwardFrom(2),
+			wantQDesc: true,
+		},
+		{
+			name:     "empty page",
+			params:   pageQuery{Limit: 2, Cursor: forwardFrom(5)},
+			rows:     []int{},
+			wantRows: []int{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.params.queryDesc() != test.wantQDesc {
+				t.Errorf("Expected queryDesc %v, got %v.", test.wantQDesc, test.params.queryDesc())
+			}
+			if test.params.queryLimit() != test.params.Limit+1 {
+				t.Errorf("Expected queryLimit to fetch one extra row, got %v.", test.params.queryLimit())
+			}
+			rows, next, prev := paginate(test.params, test.rows, cursorFor)
+			if !reflect.DeepEqual(rows, test.wantRows) {
+				t.Errorf("Expected rows %v, got %v.", test.wantRows, rows)
+			}
+			if !reflect.DeepEqual(next, test.wantNext) {
+				t.Errorf("Expected next cursor %+v, got %+v.", test.wantNext, next)
+			}
+			if !reflect.DeepEqual(prev, test.wantPrev) {
+				t.Errorf("Expected prev cursor %+v, got %+v.", test.wantPrev, prev)
+			}
+		})
+	}
+}
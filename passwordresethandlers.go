@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/Mr-Rafael/chirpy/internal/mailer"
+)
+
+const passwordResetTokenLifetime = 1 * time.Hour
+
+// Reset requests send mail to someone else's inbox, so they are throttled
+// per address as well as per caller, and can't be used to flood a user.
+var (
+	passwordResetAddressThrottle = throttlePolicy{backoffAfter: 3, lockoutAfter: 5, lockout: 1 * time.Hour}
+	passwordResetIPThrottle      = throttlePolicy{backoffAfter: 10, lockoutAfter: 30, lockout: 1 * time.Hour}
+)
+
+type passwordResetRequestParams struct {
+	Email string `json:"email"`
+}
+
+type passwordResetConfirmParams struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// handlerPasswordResetRequest mails a reset token to the address if it
+// belongs to a user. It answers the same way either way, and does the lookup
+// and sending after answering, so neither the response nor its timing shows
+// which addresses have accounts.
+func (c *apiConfig) handlerPasswordResetRequest(writer http.ResponseWriter, request *http.Request) {
+	decoder := json.NewDecoder(request.Body)
+	reqParams := passwordResetRequestParams{}
+	err := decoder.Decode(&reqParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to decode the request: %v", err), "Something went wrong", http.StatusBadRequest)
+		return
+	}
+	if len(reqParams.Email) <= 0 {
+		respondWithError(writer, "The email came empty.", "Missing param: email", http.StatusBadRequest)
+		return
+	}
+
+	for _, throttle := range []struct {
+		policy throttlePolicy
+		key    string
+	}{
+		{passwordResetAddressThrottle, "reset:" + accountThrottleKey(reqParams.Email)},
+		{passwordResetIPThrottle, "reset:" + ipThrottleKey(clientIP(request))},
+	} {
+		_, retryAfter, err := c.countLoginAttempt(throttle.policy, throttle.key)
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("Failed to check password reset throttling: %v", err), "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if retryAfter > 0 {
+			respondWithTooManyRequests(writer, retryAfter)
+			return
+		}
+	}
+
+	go c.sendPasswordReset(reqParams.Email)
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// sendPasswordReset creates a reset token for the user with this address and
+// mails it to them. It runs in the background, so failures are only logged.
+func (c *apiConfig) sendPasswordReset(email string) {
+	userData, err := c.db.GetUser(context.Background(), email)
+	if err != nil {
+		fmt.Printf("Password reset requested for unknown email: %v\n", err)
+		return
+	}
+
+	token, err := auth.MakeOpaqueToken()
+	if err != nil {
+		fmt.Printf("[Error]: Failed to generate the reset token: %v\n", err)
+		return
+	}
+	createParams := database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userData.ID,
+		ExpiresAt: time.Now().Add(passwordResetTokenLifetime),
+	}
+	err = c.db.CreatePasswordResetToken(context.Background(), createParams)
+	if err != nil {
+		fmt.Printf("[Error]: Failed to save the reset token: %v\n", err)
+		return
+	}
+
+	err = c.mailer.Send(mailer.Message{
+		To:      userData.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Chirpy account.\n\n"+
+			"Within the next hour, send this token with your new password to %v/api/password-reset/confirm:\n%v\n\n"+
+			"If this wasn't you, you can ignore this email.\n", c.publicURL, token),
+	})
+	if err != nil {
+		fmt.Printf("[Error]: Failed to send the password reset email: %v\n", err)
+	}
+}
+
+func (c *apiConfig) handlerPasswordResetConfirm(writer http.ResponseWriter, request *http.Request) {
+	decoder := json.NewDecoder(request.Body)
+	reqParams := passwordResetConfirmParams{}
+	err := decoder.Decode(&reqParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to decode the request: %v", err), "Something went wrong", http.StatusBadRequest)
+		return
+	}
+	if len(reqParams.Token) <= 0 {
+		respondWithError(writer, "The reset token came empty.", "Missing param: token", http.StatusBadRequest)
+		return
+	}
+	if len(reqParams.Password) <= 0 {
+		respondWithError(writer, "The password came empty.", "Missing param: password", http.StatusBadRequest)
+		return
+	}
+
+	tokenData, err := c.db.GetPasswordResetToken(context.Background(), auth.HashToken(reqParams.Token))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("The reset token wasn't found: %v", err), "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	if tokenData.UsedAt.Valid {
+		respondWithError(writer, fmt.Sprintf("The reset token was already used on %v", tokenData.UsedAt.Time), "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	if tokenData.ExpiresAt.Before(time.Now()) {
+		respondWithError(writer, fmt.Sprintf("The reset token expired on %v", tokenData.ExpiresAt), "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(reqParams.Password)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("failed to hash the password: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	err = c.resetPassword(tokenData, hashedPassword)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to reset the password: %v", err), "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+
+	c.logSecurityEvent(tokenData.UserID, "password_reset", "The password was reset and every session and personal access token was revoked.")
+	userData, err := c.db.GetUserByID(context.Background(), tokenData.UserID)
+	if err == nil {
+		c.clearLoginFailures(userData.Email)
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// resetPassword uses up the token, sets the new password and signs out every
+// session and personal access token, since whoever held the old password may
+// still be logged in or have minted one.
+func (c *apiConfig) resetPassword(tokenData database.PasswordResetToken, hashedPassword string) error {
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	used, err := qtx.UsePasswordResetToken(context.Background(), tokenData.TokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to use the reset token: %v", err)
+	}
+	if used == 0 {
+		return fmt.Errorf("the reset token was used by a concurrent request")
+	}
+	_, err = qtx.UpdateUser(context.Background(), database.UpdateUserParams{
+		ID:             tokenData.UserID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update the password: %v", err)
+	}
+	err = qtx.InvalidatePasswordResetTokens(context.Background(), tokenData.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate other reset tokens: %v", err)
+	}
+	err = qtx.RevokeAllUserRefreshTokens(context.Background(), tokenData.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke the refresh tokens: %v", err)
+	}
+	err = qtx.RevokeAllUserPersonalAccessTokens(context.Background(), tokenData.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke the personal access tokens: %v", err)
+	}
+	return tx.Commit()
+}
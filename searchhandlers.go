@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+var searchTokenPattern = regexp.MustCompile(`-?"[^"]*"?|\S+`)
+
+// buildTSQuery turns a search box string into to_tsquery syntax. Words are
+// ANDed together, "quoted text" is a phrase, a trailing * makes a prefix
+// match, a leading - negates and OR between two terms matches either. Any
+// other tsquery syntax is stripped so user input can't produce a query error.
+func buildTSQuery(input string) string {
+	query := ""
+	joiner := " & "
+	for _, token := range searchTokenPattern.FindAllString(input, -1) {
+		if token == "OR" {
+			if query != "" {
+				joiner = " | "
+			}
+			continue
+		}
+
+		negate := strings.HasPrefix(token, "-")
+		token = strings.TrimPrefix(token, "-")
+		phrase := strings.HasPrefix(token, `"`)
+		token = strings.Trim(token, `"`)
+		prefix := !phrase && strings.HasSuffix(token, "*")
+
+		words := strings.FieldsFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			continue
+		}
+		term := strings.Join(words, " <-> ")
+		if prefix {
+			term += ":*"
+		}
+		if len(words) > 1 {
+			term = "(" + term + ")"
+		}
+		if negate {
+			term = "!" + term
+		}
+
+		if query != "" {
+			query += joiner
+		}
+		query += term
+		joiner = " & "
+	}
+	return query
+}
+
+// parseSearchTime reads an RFC 3339 timestamp or a bare date for the from and
+// to filters. The upper bound is exclusive, so with endOfDay a bare date
+// points at the end of that day and the day itself is still searched.
+func parseSearchTime(value string, endOfDay bool) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return sql.NullTime{Time: parsed.UTC(), Valid: true}, nil
+	}
+	parsed, err = time.Parse("2006-01-02", value)
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("invalid date: %v", value)
+	}
+	if endOfDay {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return sql.NullTime{Time: parsed, Valid: true}, nil
+}
+
+func (c *apiConfig) handlerSearchChirps(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+
+	tsQuery := buildTSQuery(query.Get("q"))
+	if len(tsQuery) == 0 {
+		respondWithError(writer, "The search query came empty.", "Missing param: q", http.StatusBadRequest)
+		return
+	}
+
+	pageParams, err := parsePageParams(query)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the pagination params: %v", err), "Invalid pagination params", http.StatusBadRequest)
+		return
+	}
+	// Search results are always ordered by relevance, best match first.
+	pageParams.Desc = true
+
+	authorID := uuid.NullUUID{}
+	if query.Get("author_id") != "" {
+		authorUUID, err := uuid.Parse(query.Get("author_id"))
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("Failed to parse the author id: %v", err), "Invalid author_id", http.StatusBadRequest)
+			return
+		}
+		authorID = uuid.NullUUID{UUID: authorUUID, Valid: true}
+	}
+	createdFrom, err := parseSearchTime(query.Get("from"), false)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the from date: %v", err), "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	createdTo, err := parseSearchTime(query.Get("to"), true)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the to date: %v", err), "Invalid to date", http.StatusBadRequest)
+		return
+	}
+
+	cursorRank := sql.NullFloat64{}
+	if pageParams.Cursor != nil {
+		cursorRank = sql.NullFloat64{Float64: float64(pageParams.Cursor.Rank), Valid: true}
+	}
+	cursorCreatedAt, cursorID := pageParams.cursorArgs()
+	viewerID := c.optionalUserID(request)
+
+	var queryResult []database.SearchChirpsRow
+	if pageParams.queryDesc() {
+		queryResult, err = c.db.SearchChirps(context.Background(), database.SearchChirpsParams{
+			Query:           tsQuery,
+			AuthorID:        authorID,
+			CreatedFrom:     createdFrom,
+			CreatedTo:       createdTo,
+			CursorRank:      cursorRank,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			ViewerID:        viewerID,
+			Limit:           pageParams.queryLimit(),
+		})
+	} else {
+		var reverseResult []database.SearchChirpsReverseRow
+		reverseResult, err = c.db.SearchChirpsReverse(context.Background(), database.SearchChirpsReverseParams{
+			Query:           tsQuery,
+			AuthorID:        authorID,
+			CreatedFrom:     createdFrom,
+			CreatedTo:       createdTo,
+			CursorRank:      cursorRank,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			ViewerID:        viewerID,
+			Limit:           pageParams.queryLimit(),
+		})
+		for _, row := range reverseResult {
+			queryResult = append(queryResult, database.SearchChirpsRow(row))
+		}
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error searching chirps in the database: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	queryResult, nextCursor, prevCursor := paginate(pageParams, queryResult, func(row database.SearchChirpsRow) pageCursor {
+		return pageCursor{CreatedAt: row.CreatedAt, ID: row.ID, Rank: row.Rank}
+	})
+
+	chirps := []chirpRow{}
+	for _, row := range queryResult {
+		chirps = append(chirps, chirpRow{
+			ID:        row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body:      row.Body,
+			UserID:    row.UserID,
+			InReplyTo: row.InReplyTo,
+			RechirpOf: row.RechirpOf,
+			QuoteOf:   row.QuoteOf,
+		})
+	}
+	responseData, err := c.chirpResponses(viewerID, chirps)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error building the chirps response: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	setPageLinks(writer, request, pageParams, nextCursor, prevCursor)
+	respondWithJSON(writer, responseData, http.StatusOK)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildTSQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "empty", input: "   ", want: ""},
+		{name: "words are ANDed", input: "go sql", want: "go & sql"},
+		{name: "phrase", input: `"hello world"`, want: "(hello <-> world)"},
+		{name: "unterminated phrase", input: `"hello world`, want: "(hello <-> world)"},
+		{name: "prefix", input: "chirp*", want: "chirp:*"},
+		{name: "negation", input: "go -java", want: "go & !java"},
+		{name: "negated phrase", input: `-"bad news"`, want: "!(bad <-> news)"},
+		{name: "OR", input: "cats OR dogs", want: "cats | dogs"},
+		{name: "OR only joins its neighbours", input: "cats OR dogs birds", want: "cats | dogs & birds"},
+		{name: "leading OR is ignored", input: "OR cats", want: "cats"},
+		{name: "lowercase or is a word", input: "cats or dogs", want: "cats & or & dogs"},
+		{name: "tsquery syntax is stripped", input: "a&b | !c:* (d)", want: "(a <-> b) & c:* & d"},
+		{name: "only punctuation", input: "!!! ()", want: ""},
+		{name: "unicode", input: "café 日本", want: "café & 日本"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := buildTSQuery(test.input)
+			if got != test.want {
+				t.Errorf("Expected %q, got %q.", test.want, got)
+			}
+		})
+	}
+}
+
+func TestParseSearchTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		endOfDay  bool
+		wantErr   bool
+		wantValid bool
+		want      time.Time
+	}{
+		{name: "empty", value: ""},
+		{name: "timestamp in UTC", value: "2026-10-17T10:00:00+02:00", wantValid: true, want: time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)},
+		{name: "timestamp ignores end of day", value: "2026-10-17T10:00:00Z", endOfDay: true, wantValid: true, want: time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)},
+		{name: "bare date starts the day", value: "2026-10-17", wantValid: true, want: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{name: "bare date ends the day", value: "2026-10-17", endOfDay: true, wantValid: true, want: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{name: "not a date", value: "yesterday", wantErr: true},
+		{name: "impossible date", value: "2026-02-30", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseSearchTime(test.value, test.endOfDay)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Expected %q to be rejected.", test.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.Valid != test.wantValid || !got.Time.Equal(test.want) {
+				t.Errorf("Expected %v, got %v.", test.want, got.Time)
+			}
+		})
+	}
+}
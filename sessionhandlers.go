@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// A session is a refresh token family: it starts at login and is carried
+// forward by every refresh, so its active token tells us when it was last used.
+type sessionResponseParams struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (c *apiConfig) handlerSessionsGET(writer http.ResponseWriter, request *http.Request) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := c.db.ListActiveSessions(context.Background(), jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get the sessions from the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(writer, newSessionResponses(sessions), http.StatusOK)
+}
+
+func (c *apiConfig) handlerSessionDELETE(writer http.ResponseWriter, request *http.Request) {
+	sessionID, err := uuid.Parse(request.PathValue("session_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the session id: %v", err), "Session not found", http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	revokeParams := database.RevokeUserSessionParams{
+		FamilyID: sessionID,
+		UserID:   jwt_user_id,
+	}
+	revoked, err := c.db.RevokeUserSession(context.Background(), revokeParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to revoke the session: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(writer, fmt.Sprintf("No active session %v for user %v.", sessionID, jwt_user_id), "Session not found", http.StatusNotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *apiConfig) handlerLogoutAll(writer http.ResponseWriter, request *http.Request) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err = c.db.RevokeAllUserRefreshTokens(context.Background(), jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to revoke the refresh tokens: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func newSessionResponses(sessions []database.ListActiveSessionsRow) []sessionResponseParams {
+	responses := make([]sessionResponseParams, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, sessionResponseParams{
+			ID:         session.FamilyID,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IpAddress,
+			ExpiresAt:  session.ExpiresAt,
+		})
+	}
+	return responses
+}
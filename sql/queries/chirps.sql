@@ -1,30 +1,157 @@
 -- name: CreateChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, quote_of)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of;
+
+-- name: ImportChirp :one
 INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $1,
+    $2,
+    $3
+)
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of;
+
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
+    '',
     $1,
     $2
 )
-RETURNING *;
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of;
 
 -- name: GetChirps :many
-SELECT *
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of
 FROM chirps
 ORDER BY created_at ASC;
 
 -- name: GetChirpsByUser :many
-SELECT *
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of
 FROM chirps
 WHERE user_id = $1
 ORDER BY created_at ASC;
 
+-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of
+FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('in_reply_to')::uuid IS NULL OR in_reply_to = sqlc.narg('in_reply_to')::uuid)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+  AND (
+    sqlc.narg('viewer_id')::uuid IS NULL
+    OR NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+         OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+    )
+  )
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of
+FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('in_reply_to')::uuid IS NULL OR in_reply_to = sqlc.narg('in_reply_to')::uuid)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+  AND (
+    sqlc.narg('viewer_id')::uuid IS NULL
+    OR NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+         OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+    )
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
 -- name: GetChirp :one
-SELECT *
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of
 FROM chirps
 WHERE id = $1;
 
+-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of
+FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+  AND (
+    sqlc.narg('viewer_id')::uuid IS NULL
+    OR NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+         OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+    )
+  );
+
+-- name: UpdateChirp :one
+WITH revision AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+    SELECT gen_random_uuid(), id, body, updated_at, NOW()
+    FROM chirps
+    WHERE chirps.id = $1
+)
+UPDATE chirps
+SET body = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id, parent.in_reply_to, 1 AS depth
+    FROM chirps parent
+    JOIN chirps child ON child.in_reply_to = parent.id
+    WHERE child.id = sqlc.arg('id')
+      AND (
+        sqlc.narg('viewer_id')::uuid IS NULL
+        OR NOT EXISTS (
+          SELECT 1
+          FROM blocks
+          WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = parent.user_id)
+             OR (blocks.blocker_id = parent.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+        )
+      )
+    UNION ALL
+    SELECT chirps.id, chirps.in_reply_to, ancestors.depth + 1
+    FROM chirps
+    JOIN ancestors ON chirps.id = ancestors.in_reply_to
+    WHERE sqlc.narg('viewer_id')::uuid IS NULL
+       OR NOT EXISTS (
+         SELECT 1
+         FROM blocks
+         WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = chirps.user_id)
+            OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+       )
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of
+FROM chirps
+JOIN ancestors ON chirps.id = ancestors.id
+ORDER BY ancestors.depth DESC;
+
 -- name: DeleteChirp :exec
 DELETE FROM chirps
 WHERE id = $1;
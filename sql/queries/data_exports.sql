@@ -0,0 +1,37 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, user_id, status, created_at, completed_at, downloaded_at, expires_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    'pending',
+    NOW(),
+    NULL,
+    NULL,
+    $2
+)
+RETURNING *;
+
+-- name: GetDataExport :one
+SELECT * FROM data_exports
+WHERE id = $1 AND user_id = $2;
+
+-- name: FinishDataExport :exec
+UPDATE data_exports
+SET status = $2,
+    completed_at = NOW()
+WHERE id = $1;
+
+-- name: FailPendingDataExports :exec
+UPDATE data_exports
+SET status = 'failed',
+    completed_at = NOW()
+WHERE status = 'pending';
+
+-- name: MarkDataExportDownloaded :execrows
+UPDATE data_exports
+SET downloaded_at = NOW()
+WHERE id = $1 AND status = 'ready' AND downloaded_at IS NULL AND expires_at > NOW();
+
+-- name: DeleteExpiredDataExports :exec
+DELETE FROM data_exports
+WHERE expires_at <= $1;
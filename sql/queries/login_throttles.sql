@@ -0,0 +1,37 @@
+-- name: RecordLoginAttempt :one
+INSERT INTO login_throttles (key, failures, last_failure_at, locked_until)
+VALUES (
+    sqlc.arg('key'),
+    1,
+    sqlc.arg('attempted_at'),
+    NULL
+)
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.locked_until > sqlc.arg('attempted_at') THEN login_throttles.failures
+        WHEN login_throttles.last_failure_at < sqlc.arg('window_start') THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = CASE
+        WHEN login_throttles.locked_until > sqlc.arg('attempted_at') THEN login_throttles.last_failure_at
+        ELSE EXCLUDED.last_failure_at
+    END
+RETURNING failures, locked_until;
+
+-- name: RefundLoginAttempt :exec
+UPDATE login_throttles
+SET failures = GREATEST(failures - 1, 0),
+    locked_until = CASE
+        WHEN locked_until = sqlc.narg('locked_until') THEN NULL
+        ELSE locked_until
+    END
+WHERE key = sqlc.arg('key');
+
+-- name: LockLogin :exec
+UPDATE login_throttles
+SET locked_until = $2
+WHERE key = $1;
+
+-- name: ClearLoginThrottle :execrows
+DELETE FROM login_throttles
+WHERE key = $1;
@@ -0,0 +1,17 @@
+-- name: CreateChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+SELECT sqlc.arg('chirp_id'), users.id
+FROM users
+JOIN chirps ON chirps.id = sqlc.arg('chirp_id')
+WHERE LOWER(users.username) = ANY(sqlc.arg('usernames')::text[])
+  AND NOT EXISTS (
+    SELECT 1
+    FROM blocks
+    WHERE (blocks.blocker_id = users.id AND blocks.blocked_id = chirps.user_id)
+       OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = users.id)
+  )
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
@@ -0,0 +1,65 @@
+-- name: CreateMentionNotifications :exec
+INSERT INTO notifications (id, created_at, user_id, actor_id, chirp_id, kind, read_at)
+SELECT gen_random_uuid(), NOW(), chirp_mentions.user_id, chirps.user_id, chirps.id, 'mention', NULL
+FROM chirp_mentions
+JOIN chirps ON chirps.id = chirp_mentions.chirp_id
+WHERE chirp_mentions.chirp_id = $1
+  AND chirp_mentions.user_id <> chirps.user_id
+ON CONFLICT (user_id, chirp_id, kind) DO NOTHING;
+
+-- name: ListNotificationsAsc :many
+SELECT *
+FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+  AND (NOT sqlc.arg('unread_only')::boolean OR read_at IS NULL)
+  AND NOT EXISTS (
+    SELECT 1
+    FROM blocks
+    WHERE (blocks.blocker_id = notifications.user_id AND blocks.blocked_id = notifications.actor_id)
+       OR (blocks.blocker_id = notifications.actor_id AND blocks.blocked_id = notifications.user_id)
+  )
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListNotificationsDesc :many
+SELECT *
+FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+  AND (NOT sqlc.arg('unread_only')::boolean OR read_at IS NULL)
+  AND NOT EXISTS (
+    SELECT 1
+    FROM blocks
+    WHERE (blocks.blocker_id = notifications.user_id AND blocks.blocked_id = notifications.actor_id)
+       OR (blocks.blocker_id = notifications.actor_id AND blocks.blocked_id = notifications.user_id)
+  )
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*)
+FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1
+    FROM blocks
+    WHERE (blocks.blocker_id = notifications.user_id AND blocks.blocked_id = notifications.actor_id)
+       OR (blocks.blocker_id = notifications.actor_id AND blocks.blocked_id = notifications.user_id)
+  );
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
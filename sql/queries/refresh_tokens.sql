@@ -1,25 +1,71 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, user_agent, ip_address)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
     $3,
-    NULL
+    NULL,
+    $4,
+    $5,
+    $6
 )
 RETURNING *;
 
 -- name: GetRefreshToken :one
 SELECT *
 FROM refresh_tokens
-WHERE $1 = token;
+WHERE $1 = token_hash;
 
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE $1 = token;
+WHERE $1 = token_hash;
+
+-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW(),
+    replaced_by = sqlc.arg('replaced_by')
+WHERE token_hash = sqlc.arg('token_hash') AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = COALESCE(revoked_at, NOW()),
+    updated_at = NOW()
+WHERE family_id = $1;
+
+-- name: ListActiveSessions :many
+SELECT active.family_id,
+    (SELECT MIN(family.created_at) FROM refresh_tokens family WHERE family.family_id = active.family_id)::timestamp AS created_at,
+    active.created_at AS last_used_at,
+    active.user_agent,
+    active.ip_address,
+    active.expires_at
+FROM refresh_tokens active
+WHERE active.user_id = $1
+  AND active.revoked_at IS NULL
+  AND active.expires_at > NOW()
+ORDER BY active.created_at DESC;
+
+-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeAllUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
 
 -- name: ResetRefreshTokens :exec
-DELETE FROM refresh_tokens;
\ No newline at end of file
+DELETE FROM refresh_tokens;
+
+-- name: GetRefreshTokensByUser :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at ASC;
@@ -0,0 +1,47 @@
+-- name: SearchChirps :many
+SELECT ranked.id, ranked.created_at, ranked.updated_at, ranked.body, ranked.user_id, ranked.in_reply_to, ranked.rechirp_of, ranked.quote_of, ranked.rank
+FROM (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, ts_rank(chirps.body_tsv, to_tsquery('english', sqlc.arg('query')))::real AS rank
+    FROM chirps
+    WHERE chirps.body_tsv @@ to_tsquery('english', sqlc.arg('query'))
+      AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+      AND (sqlc.narg('created_from')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('created_from')::timestamp)
+      AND (sqlc.narg('created_to')::timestamp IS NULL OR chirps.created_at < sqlc.narg('created_to')::timestamp)
+) ranked
+WHERE (sqlc.narg('cursor_rank')::real IS NULL
+   OR (ranked.rank, ranked.created_at, ranked.id) < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+  AND (
+    sqlc.narg('viewer_id')::uuid IS NULL
+    OR NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = ranked.user_id)
+         OR (blocks.blocker_id = ranked.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+    )
+  )
+ORDER BY ranked.rank DESC, ranked.created_at DESC, ranked.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchChirpsReverse :many
+SELECT ranked.id, ranked.created_at, ranked.updated_at, ranked.body, ranked.user_id, ranked.in_reply_to, ranked.rechirp_of, ranked.quote_of, ranked.rank
+FROM (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, ts_rank(chirps.body_tsv, to_tsquery('english', sqlc.arg('query')))::real AS rank
+    FROM chirps
+    WHERE chirps.body_tsv @@ to_tsquery('english', sqlc.arg('query'))
+      AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+      AND (sqlc.narg('created_from')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('created_from')::timestamp)
+      AND (sqlc.narg('created_to')::timestamp IS NULL OR chirps.created_at < sqlc.narg('created_to')::timestamp)
+) ranked
+WHERE (sqlc.narg('cursor_rank')::real IS NULL
+   OR (ranked.rank, ranked.created_at, ranked.id) > (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+  AND (
+    sqlc.narg('viewer_id')::uuid IS NULL
+    OR NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND blocks.blocked_id = ranked.user_id)
+         OR (blocks.blocker_id = ranked.user_id AND blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+    )
+  )
+ORDER BY ranked.rank ASC, ranked.created_at ASC, ranked.id ASC
+LIMIT sqlc.arg('limit');
@@ -1,18 +1,55 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username, display_name, bio)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
 RETURNING *;
 
 -- name: UpdateUser :one
 UPDATE users
+SET hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET username = COALESCE(sqlc.narg('username'), username),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ScheduleUserDeletion :one
+UPDATE users
+SET delete_after = COALESCE(delete_after, $2),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelUserDeletion :exec
+UPDATE users
+SET delete_after = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteUsersPastGracePeriod :many
+DELETE FROM users
+WHERE delete_after <= $1
+RETURNING id;
+
+-- name: VerifyUserEmail :one
+UPDATE users
 SET email = $2,
-    hashed_password = $3,
+    email_verified_at = NOW(),
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
@@ -22,6 +59,22 @@ SELECT *
 FROM users
 WHERE email = $1;
 
+-- name: GetUserByID :one
+SELECT *
+FROM users
+WHERE id = $1;
+
+-- name: GetUserByUsername :one
+SELECT *
+FROM users
+WHERE LOWER(username) = LOWER(sqlc.arg('username'));
+
+-- name: GetUserProfileCounts :one
+SELECT
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = $1) AS chirp_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = $1) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = $1) AS following_count;
+
 -- name: UpgradeUser :exec
 UPDATE users
 SET is_chirpy_red = TRUE
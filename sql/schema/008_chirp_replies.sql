@@ -0,0 +1,14 @@
+-- +goose Up
+-- in_reply_to deliberately has no foreign key: replies outlive a deleted
+-- parent and keep pointing at it so clients can render "reply to a deleted
+-- chirp" instead of silently losing the thread.
+ALTER TABLE chirps
+ADD COLUMN in_reply_to UUID;
+
+CREATE INDEX chirps_in_reply_to_created_at_id_idx ON chirps (in_reply_to, created_at, id);
+
+-- +goose Down
+DROP INDEX chirps_in_reply_to_created_at_id_idx;
+
+ALTER TABLE chirps
+DROP COLUMN in_reply_to;
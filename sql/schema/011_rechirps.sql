@@ -0,0 +1,15 @@
+-- +goose Up
+-- Like in_reply_to, these have no foreign key so rechirps and quotes survive
+-- the original being deleted.
+ALTER TABLE chirps
+ADD COLUMN rechirp_of UUID,
+ADD COLUMN quote_of UUID;
+
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_idx ON chirps (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_user_id_rechirp_of_idx;
+
+ALTER TABLE chirps
+DROP COLUMN quote_of,
+DROP COLUMN rechirp_of;
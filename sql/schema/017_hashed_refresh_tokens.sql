@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+UPDATE refresh_tokens
+SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex'),
+    replaced_by = encode(sha256(convert_to(replaced_by, 'UTF8')), 'hex');
+
+-- +goose Down
+-- The raw tokens can't be recovered from their digests, so every session
+-- stored while this migration was applied stops working after a rollback.
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
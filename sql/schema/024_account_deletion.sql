@@ -0,0 +1,13 @@
+-- +goose Up
+-- delete_after is set when a user asks to delete their account. Logging in
+-- before then clears it; once it passes, the user is deleted for good.
+ALTER TABLE users
+ADD COLUMN delete_after TIMESTAMP;
+
+CREATE INDEX users_delete_after_idx ON users (delete_after) WHERE delete_after IS NOT NULL;
+
+-- +goose Down
+DROP INDEX users_delete_after_idx;
+
+ALTER TABLE users
+DROP COLUMN delete_after;
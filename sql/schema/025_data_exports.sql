@@ -0,0 +1,18 @@
+-- +goose Up
+-- The archive itself lives on disk as <id>.zip in the export directory; this
+-- table tracks its progress and when it can be thrown away.
+CREATE TABLE data_exports (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    downloaded_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX data_exports_user_id_pending_idx ON data_exports (user_id) WHERE status = 'pending';
+CREATE INDEX data_exports_expires_at_idx ON data_exports (expires_at);
+
+-- +goose Down
+DROP TABLE data_exports;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Keeps a chirp's hashtags in the order they appear in its body. Rows from
+-- before this all get 0 and fall back to alphabetical order.
+ALTER TABLE chirp_hashtags ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE chirp_hashtags DROP COLUMN position;
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Mr-Rafael/chirpy/internal/auth"
+	"github.com/Mr-Rafael/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxTokenNameLength = 100
+
+type tokenRequestParams struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+type tokenResponseParams struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+// Personal access tokens are managed with a JWT only, so a leaked token can't
+// be used to mint more tokens or widen its own scopes.
+func (c *apiConfig) handlerTokensPOST(writer http.ResponseWriter, request *http.Request) {
+	decoder := json.NewDecoder(request.Body)
+	reqParams := tokenRequestParams{}
+	err := decoder.Decode(&reqParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to decode the request: %v", err), "Something went wrong", http.StatusBadRequest)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	name := strings.TrimSpace(reqParams.Name)
+	if name == "" || len(name) > maxTokenNameLength {
+		respondWithError(writer, fmt.Sprintf("Invalid token name: %q", reqParams.Name), fmt.Sprintf("Token name must be 1 to %v characters long", maxTokenNameLength), http.StatusBadRequest)
+		return
+	}
+	if len(reqParams.Scopes) == 0 {
+		respondWithError(writer, "A token was requested without scopes.", "At least one scope is required", http.StatusBadRequest)
+		return
+	}
+	for _, scope := range reqParams.Scopes {
+		if !slices.Contains(validScopes, scope) {
+			respondWithError(writer, fmt.Sprintf("Unknown scope requested: %v", scope), fmt.Sprintf("Unknown scope: %v", scope), http.StatusBadRequest)
+			return
+		}
+	}
+	if reqParams.ExpiresInDays < 0 {
+		respondWithError(writer, fmt.Sprintf("Invalid token lifetime: %v", reqParams.ExpiresInDays), "expires_in_days can't be negative", http.StatusBadRequest)
+		return
+	}
+	expiresAt := sql.NullTime{}
+	if reqParams.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, reqParams.ExpiresInDays), Valid: true}
+	}
+
+	token, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to generate the token: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	slices.Sort(reqParams.Scopes)
+	createParams := database.CreatePersonalAccessTokenParams{
+		UserID:    jwt_user_id,
+		Name:      name,
+		TokenHash: auth.HashToken(token),
+		Scopes:    slices.Compact(reqParams.Scopes),
+		ExpiresAt: expiresAt,
+	}
+	tokenData, err := c.db.CreatePersonalAccessToken(context.Background(), createParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to save the token to the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	respBody := newTokenResponse(tokenData)
+	respBody.Token = token
+	respondWithJSON(writer, respBody, http.StatusCreated)
+}
+
+func (c *apiConfig) handlerTokensGET(writer http.ResponseWriter, request *http.Request) {
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := c.db.ListPersonalAccessTokens(context.Background(), jwt_user_id)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get the tokens from the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	respBody := make([]tokenResponseParams, 0, len(tokens))
+	for _, tokenData := range tokens {
+		respBody = append(respBody, newTokenResponse(tokenData))
+	}
+	respondWithJSON(writer, respBody, http.StatusOK)
+}
+
+func (c *apiConfig) handlerTokenDELETE(writer http.ResponseWriter, request *http.Request) {
+	tokenID, err := uuid.Parse(request.PathValue("token_id"))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to parse the token id: %v", err), "Token not found", http.StatusNotFound)
+		return
+	}
+
+	bearerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if jwt_user_id == uuid.Nil {
+		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	revokeParams := database.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: jwt_user_id,
+	}
+	revoked, err := c.db.RevokePersonalAccessToken(context.Background(), revokeParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to revoke the token: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(writer, fmt.Sprintf("No active token %v for user %v.", tokenID, jwt_user_id), "Token not found", http.StatusNotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func newTokenResponse(tokenData database.PersonalAccessToken) tokenResponseParams {
+	response := tokenResponseParams{
+		ID:        tokenData.ID,
+		Name:      tokenData.Name,
+		Scopes:    tokenData.Scopes,
+		CreatedAt: tokenData.CreatedAt,
+	}
+	if tokenData.ExpiresAt.Valid {
+		response.ExpiresAt = &tokenData.ExpiresAt.Time
+	}
+	if tokenData.LastUsedAt.Valid {
+		response.LastUsedAt = &tokenData.LastUsedAt.Time
+	}
+	return response
+}
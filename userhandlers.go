@@ -2,19 +2,49 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Mr-Rafael/chirpy/internal/auth"
 	"github.com/Mr-Rafael/chirpy/internal/database"
 	"github.com/google/uuid"
 )
 
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	accessTokenLifetime  = 1 * time.Hour
+	refreshTokenLifetime = 60 * 24 * time.Hour
+)
+
+var errRefreshTokenReused = errors.New("refresh token was already rotated")
+
 type usersRequestParams struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+}
+
+// usersPatchRequestParams leaves out any field the client didn't send, so
+// only the given ones are changed.
+type usersPatchRequestParams struct {
+	CurrentPassword string  `json:"current_password"`
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	Username        *string `json:"username"`
+	DisplayName     *string `json:"display_name"`
+	Bio             *string `json:"bio"`
 }
 
 type loginRequestParams struct {
@@ -23,25 +53,55 @@ type loginRequestParams struct {
 }
 
 type usersResponseParams struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	PendingEmail  string    `json:"pending_email,omitempty"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	Username      string    `json:"username,omitempty"`
+	DisplayName   string    `json:"display_name"`
+	Bio           string    `json:"bio"`
+}
+
+type userProfileResponseParams struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Username       string    `json:"username,omitempty"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	ChirpCount     int64     `json:"chirp_count"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
 }
 
 type loginResponseParams struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	IsChirpyRed  bool      `json:"is_chirpy_red"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	Username      string    `json:"username,omitempty"`
+	DisplayName   string    `json:"display_name"`
+	Bio           string    `json:"bio"`
+	Token         string    `json:"token"`
+	RefreshToken  string    `json:"refresh_token"`
+}
+
+// usersPatchResponseParams carries a fresh session when the password
+// changed, since every previous one was signed out.
+type usersPatchResponseParams struct {
+	usersResponseParams
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
 type refreshResponseParams struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 func (c *apiConfig) handlerUsers(writer http.ResponseWriter, request *http.Request) {
@@ -60,6 +120,26 @@ func (c *apiConfig) handlerUsers(writer http.ResponseWriter, request *http.Reque
 		respondWithError(writer, fmt.Sprintf("The password came empty: %v", err), "Missing param: password", http.StatusBadRequest)
 		return
 	}
+	if !isValidEmail(reqParams.Email) {
+		respondWithError(writer, fmt.Sprintf("Invalid email: %v", reqParams.Email), "Invalid email address", http.StatusBadRequest)
+		return
+	}
+	if len(reqParams.Username) <= 0 {
+		respondWithError(writer, "The username came empty.", "Missing param: username", http.StatusBadRequest)
+		return
+	}
+	if !usernamePattern.MatchString(reqParams.Username) {
+		respondWithError(writer, fmt.Sprintf("Invalid username: %v", reqParams.Username), "Usernames must be 3-30 letters, digits or underscores", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(reqParams.DisplayName) > maxDisplayNameLength {
+		respondWithError(writer, "Error: display name too long", "Display name is too long", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(reqParams.Bio) > maxBioLength {
+		respondWithError(writer, "Error: bio too long", "Bio is too long", http.StatusBadRequest)
+		return
+	}
 	hashedPassword, err := auth.HashPassword(reqParams.Password)
 	if err != nil {
 		respondWithError(writer, fmt.Sprintf("failed to hash the password: %v", err), "Something went wrong", http.StatusInternalServerError)
@@ -69,20 +149,25 @@ func (c *apiConfig) handlerUsers(writer http.ResponseWriter, request *http.Reque
 	queryParams := database.CreateUserParams{
 		Email:          reqParams.Email,
 		HashedPassword: hashedPassword,
+		Username:       sql.NullString{String: reqParams.Username, Valid: true},
+		DisplayName:    strings.TrimSpace(reqParams.DisplayName),
+		Bio:            strings.TrimSpace(reqParams.Bio),
 	}
 	queryResult, err := c.db.CreateUser(context.Background(), queryParams)
+	if isUniqueViolation(err) {
+		respondWithError(writer, fmt.Sprintf("Failed to save the user to database: %v", err), "Email or username already taken", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		respondWithError(writer, fmt.Sprintf("Failed to save the user to database: %v", err), "Something went wrong", http.StatusInternalServerError)
 		return
 	}
-
-	responseBody := usersResponseParams{
-		ID:          queryResult.ID,
-		CreatedAt:   queryResult.CreatedAt,
-		UpdatedAt:   queryResult.UpdatedAt,
-		Email:       queryResult.Email,
-		IsChirpyRed: queryResult.IsChirpyRed,
+	err = c.sendEmailVerification(queryResult.ID, queryResult.Email)
+	if err != nil {
+		fmt.Printf("[Error]: Failed to send the verification email: %v\n", err)
 	}
+
+	responseBody := newUsersResponse(queryResult)
 	respondWithJSON(writer, responseBody, http.StatusCreated)
 }
 
@@ -103,51 +188,85 @@ func (c *apiConfig) handlerLogin(writer http.ResponseWriter, request *http.Reque
 		return
 	}
 
+	clientAddress := clientIP(request)
+	attempts, retryAfter, err := c.startLoginAttempt(reqParams.Email, clientAddress)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to check login throttling: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if retryAfter > 0 {
+		respondWithTooManyAttempts(writer, retryAfter)
+		return
+	}
+
 	userData, err := c.db.GetUser(context.Background(), reqParams.Email)
 	if err != nil {
+		failLoginAttempts(attempts)
 		respondWithError(writer, fmt.Sprintf("Failed to get user data: %v", err), "User not found", http.StatusNotFound)
 		return
 	}
 
 	correctPassword, err := auth.CheckPasswordHash(reqParams.Password, userData.HashedPassword)
 	if err != nil {
+		c.refundLoginAttempts(attempts)
 		respondWithError(writer, fmt.Sprintf("Failed to compare password and hash: %v", err), "Something went wrong", http.StatusInternalServerError)
 		return
 	}
 	if !correctPassword {
+		failLoginAttempts(attempts)
 		respondWithError(writer, "Login attempt with incorrect password.", "Incorrect email or password", http.StatusUnauthorized)
 		return
 	}
+	c.refundLoginAttempts(attempts)
 
-	return_jwt, err := auth.MakeJWT(userData.ID, c.secret, 1*time.Hour)
-	if err != nil {
-		respondWithError(writer, fmt.Sprintf("Failed to generate JWT for user: %v", err), "Something went wrong.", http.StatusInternalServerError)
+	totpData, err := c.db.GetUserTOTP(context.Background(), userData.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(writer, fmt.Sprintf("Failed to get the user's TOTP settings: %v", err), "Something went wrong.", http.StatusInternalServerError)
 		return
 	}
-	refresh_token, err := auth.GenerateSecretKeyHS256()
-	if err != nil {
-		respondWithError(writer, fmt.Sprintf("Failed to generate refresh token for user: %v", err), "Something went wrong.", http.StatusInternalServerError)
+	if err == nil && totpData.ConfirmedAt.Valid {
+		c.respondWithMFAChallenge(writer, userData.ID)
 		return
 	}
-	create_refresh_token_params := database.CreateRefreshTokenParams{
-		Token:     refresh_token,
-		UserID:    userData.ID,
-		ExpiresAt: time.Now().Add(time.Duration(24 * 60 * time.Hour)),
+
+	c.respondWithLogin(writer, request, userData)
+}
+
+// respondWithLogin issues the access and refresh tokens for a user that has
+// passed every login step.
+func (c *apiConfig) respondWithLogin(writer http.ResponseWriter, request *http.Request, userData database.User) {
+	c.clearLoginFailures(userData.Email)
+	if userData.DeleteAfter.Valid {
+		err := c.cancelAccountDeletion(userData.ID)
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("Failed to cancel the account deletion: %v", err), "Something went wrong.", http.StatusInternalServerError)
+			return
+		}
 	}
-	_, err = c.db.CreateRefreshToken(context.Background(), create_refresh_token_params)
+
+	return_jwt, err := auth.MakeJWT(userData.ID, c.jwtKeys, c.jwtValidator.Issuer, c.jwtAudience, accessTokenLifetime)
 	if err != nil {
-		respondWithError(writer, fmt.Sprintf("Failed to save the refresh token to the database: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		respondWithError(writer, fmt.Sprintf("Failed to generate JWT for user: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	refresh_token, err := c.issueRefreshToken(c.db, request, userData.ID, uuid.New())
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to issue a refresh token for user: %v", err), "Something went wrong.", http.StatusInternalServerError)
 		return
 	}
 
 	responseParams := loginResponseParams{
-		ID:           userData.ID,
-		Email:        userData.Email,
-		CreatedAt:    userData.CreatedAt,
-		UpdatedAt:    userData.UpdatedAt,
-		IsChirpyRed:  userData.IsChirpyRed,
-		Token:        return_jwt,
-		RefreshToken: refresh_token,
+		ID:            userData.ID,
+		Email:         userData.Email,
+		EmailVerified: userData.EmailVerifiedAt.Valid,
+		CreatedAt:     userData.CreatedAt,
+		UpdatedAt:     userData.UpdatedAt,
+		IsChirpyRed:   userData.IsChirpyRed,
+		Username:      userData.Username.String,
+		DisplayName:   userData.DisplayName,
+		Bio:           userData.Bio,
+		Token:         return_jwt,
+		RefreshToken:  refresh_token,
 	}
 	respondWithJSON(writer, responseParams, http.StatusOK)
 }
@@ -163,11 +282,16 @@ func (c *apiConfig) handlerRefresh(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
-	tokenData, err := c.db.GetRefreshToken(context.Background(), refreshToken)
+	tokenData, err := c.db.GetRefreshToken(context.Background(), auth.HashToken(refreshToken))
 	if err != nil {
 		respondWithError(writer, "The refresh token wasn't found on the database.", "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	if tokenData.ReplacedBy.Valid {
+		c.handleRefreshTokenReuse(tokenData)
+		respondWithError(writer, fmt.Sprintf("The refresh token was already rotated on %v", tokenData.RevokedAt.Time), "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 	if tokenData.ExpiresAt.Before(time.Now()) {
 		respondWithError(writer, fmt.Sprintf("The refresh token expired on %v", tokenData.ExpiresAt), "Unauthorized", http.StatusUnauthorized)
 		return
@@ -177,18 +301,104 @@ func (c *apiConfig) handlerRefresh(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
-	return_jwt, err := auth.MakeJWT(tokenData.UserID, c.secret, 1*time.Hour)
+	new_refresh_token, err := c.rotateRefreshToken(request, tokenData)
+	if errors.Is(err, errRefreshTokenReused) {
+		c.handleRefreshTokenReuse(tokenData)
+		respondWithError(writer, "The refresh token was rotated by a concurrent request.", "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to rotate the refresh token: %v", err), "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	return_jwt, err := auth.MakeJWT(tokenData.UserID, c.jwtKeys, c.jwtValidator.Issuer, c.jwtAudience, accessTokenLifetime)
 	if err != nil {
 		respondWithError(writer, fmt.Sprintf("Failed to generate JWT for user: %v", err), "Something went wrong.", http.StatusInternalServerError)
 		return
 	}
 
 	responseParams := refreshResponseParams{
-		Token: return_jwt,
+		Token:        return_jwt,
+		RefreshToken: new_refresh_token,
 	}
 	respondWithJSON(writer, responseParams, http.StatusOK)
 }
 
+// issueRefreshToken creates and stores a new refresh token in the given
+// token family. Logging in starts a new family; refreshing continues one.
+// The client's user agent and IP are kept so the session can be listed later.
+func (c *apiConfig) issueRefreshToken(queries *database.Queries, request *http.Request, userID uuid.UUID, familyID uuid.UUID) (string, error) {
+	refresh_token, err := auth.GenerateSecretKeyHS256()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %v", err)
+	}
+	create_refresh_token_params := database.CreateRefreshTokenParams{
+		TokenHash: auth.HashToken(refresh_token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(refreshTokenLifetime),
+		FamilyID:  familyID,
+		UserAgent: request.UserAgent(),
+		IpAddress: clientIP(request),
+	}
+	_, err = queries.CreateRefreshToken(context.Background(), create_refresh_token_params)
+	if err != nil {
+		return "", fmt.Errorf("failed to save the refresh token to the database: %v", err)
+	}
+	return refresh_token, nil
+}
+
+// rotateRefreshToken revokes a refresh token and issues its replacement in
+// the same family. It returns errRefreshTokenReused if another request
+// rotated the token first.
+func (c *apiConfig) rotateRefreshToken(request *http.Request, tokenData database.RefreshToken) (string, error) {
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	new_refresh_token, err := c.issueRefreshToken(qtx, request, tokenData.UserID, tokenData.FamilyID)
+	if err != nil {
+		return "", err
+	}
+	rotated, err := qtx.RotateRefreshToken(context.Background(), database.RotateRefreshTokenParams{
+		ReplacedBy: sql.NullString{String: auth.HashToken(new_refresh_token), Valid: true},
+		TokenHash:  tokenData.TokenHash,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke the old refresh token: %v", err)
+	}
+	if rotated == 0 {
+		return "", errRefreshTokenReused
+	}
+	return new_refresh_token, tx.Commit()
+}
+
+// handleRefreshTokenReuse is called when an already rotated refresh token is
+// presented again. Either the legitimate client or an attacker holds a stolen
+// copy, and we can't tell which, so the whole family is revoked.
+func (c *apiConfig) handleRefreshTokenReuse(tokenData database.RefreshToken) {
+	err := c.db.RevokeRefreshTokenFamily(context.Background(), tokenData.FamilyID)
+	if err != nil {
+		fmt.Printf("[Error]: Failed to revoke refresh token family %v: %v\n", tokenData.FamilyID, err)
+	}
+	c.logSecurityEvent(tokenData.UserID, "refresh_token_reuse", fmt.Sprintf("Rotated refresh token from family %v was presented again; family revoked.", tokenData.FamilyID))
+}
+
+func (c *apiConfig) logSecurityEvent(userID uuid.UUID, kind string, details string) {
+	fmt.Printf("[Security]: %v for user %v: %v\n", kind, userID, details)
+	err := c.db.CreateSecurityEvent(context.Background(), database.CreateSecurityEventParams{
+		UserID:  uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		Kind:    kind,
+		Details: details,
+	})
+	if err != nil {
+		fmt.Printf("[Error]: Failed to save security event: %v\n", err)
+	}
+}
+
 func (c *apiConfig) handlerRevoke(writer http.ResponseWriter, request *http.Request) {
 	refreshToken, err := auth.GetBearerToken(request.Header)
 	if err != nil {
@@ -200,36 +410,60 @@ func (c *apiConfig) handlerRevoke(writer http.ResponseWriter, request *http.Requ
 		return
 	}
 
-	err = c.db.RevokeRefreshToken(context.Background(), refreshToken)
+	err = c.db.RevokeRefreshToken(context.Background(), auth.HashToken(refreshToken))
 	if err != nil {
 		respondWithError(writer, fmt.Sprintf("Failed to revoke the token: %v", err), "Something went wrong.", http.StatusInternalServerError)
 	}
 	writer.WriteHeader(http.StatusNoContent)
 }
 
+// handlerUsersPUT is the older form of PATCH /api/users/me that always sets
+// both the email and the password. It's deprecated but keeps its contract:
+// current_password is checked when sent and not required, so existing
+// clients keep working. The password change still signs out every other
+// session, like PATCH does.
 func (c *apiConfig) handlerUsersPUT(writer http.ResponseWriter, request *http.Request) {
 	decoder := json.NewDecoder(request.Body)
-	reqParams := usersRequestParams{}
+	reqParams := usersPatchRequestParams{}
 	err := decoder.Decode(&reqParams)
 	if err != nil {
 		respondWithError(writer, fmt.Sprintf("Failed to decode the request: %v", err), "Something went wrong", http.StatusBadRequest)
 		return
 	}
-	if len(reqParams.Email) <= 0 {
-		respondWithError(writer, fmt.Sprintf("The username came empty: %v", err), "Missing param: email", http.StatusBadRequest)
+	if reqParams.Email == nil || len(*reqParams.Email) <= 0 {
+		respondWithError(writer, "The username came empty.", "Missing param: email", http.StatusBadRequest)
 		return
 	}
-	if len(reqParams.Password) <= 0 {
-		respondWithError(writer, fmt.Sprintf("The password came empty: %v", err), "Missing param: password", http.StatusBadRequest)
+	if reqParams.Password == nil || len(*reqParams.Password) <= 0 {
+		respondWithError(writer, "The password came empty.", "Missing param: password", http.StatusBadRequest)
+		return
+	}
+	writer.Header().Set("Deprecation", "true")
+	writer.Header().Set("Link", "</api/users/me>; rel=\"successor-version\"")
+	c.updateAccount(writer, request, reqParams, len(reqParams.CurrentPassword) > 0)
+}
+
+func (c *apiConfig) handlerUsersPATCH(writer http.ResponseWriter, request *http.Request) {
+	decoder := json.NewDecoder(request.Body)
+	reqParams := usersPatchRequestParams{}
+	err := decoder.Decode(&reqParams)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to decode the request: %v", err), "Something went wrong", http.StatusBadRequest)
 		return
 	}
+	c.updateAccount(writer, request, reqParams, true)
+}
 
+// updateAccount applies the fields set in reqParams to the caller's account.
+// requirePassword makes credential changes check reqParams.CurrentPassword;
+// only the deprecated PUT /api/users goes without.
+func (c *apiConfig) updateAccount(writer http.ResponseWriter, request *http.Request, reqParams usersPatchRequestParams, requirePassword bool) {
 	bearerToken, err := auth.GetBearerToken(request.Header)
 	if err != nil {
 		respondWithError(writer, fmt.Sprintf("Failed to get bearer from request: %v", err), "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.secret)
+	jwt_user_id, err := auth.ValidateJWT(bearerToken, c.jwtKeys, c.jwtValidator)
 	if err != nil {
 		respondWithError(writer, fmt.Sprintf("Error validating JWT: %v", err), "Unauthorized", http.StatusUnauthorized)
 		return
@@ -239,29 +473,222 @@ func (c *apiConfig) handlerUsersPUT(writer http.ResponseWriter, request *http.Re
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(reqParams.Password)
+	if reqParams.Email != nil && !isValidEmail(*reqParams.Email) {
+		respondWithError(writer, fmt.Sprintf("Invalid email: %v", *reqParams.Email), "Invalid email address", http.StatusBadRequest)
+		return
+	}
+	if reqParams.Password != nil && len(*reqParams.Password) <= 0 {
+		respondWithError(writer, "The new password came empty.", "Password can't be empty", http.StatusBadRequest)
+		return
+	}
+	if reqParams.Username != nil && !usernamePattern.MatchString(*reqParams.Username) {
+		respondWithError(writer, fmt.Sprintf("Invalid username: %v", *reqParams.Username), "Usernames must be 3-30 letters, digits or underscores", http.StatusBadRequest)
+		return
+	}
+	if reqParams.DisplayName != nil && utf8.RuneCountInString(*reqParams.DisplayName) > maxDisplayNameLength {
+		respondWithError(writer, "Error: display name too long", "Display name is too long", http.StatusBadRequest)
+		return
+	}
+	if reqParams.Bio != nil && utf8.RuneCountInString(*reqParams.Bio) > maxBioLength {
+		respondWithError(writer, "Error: bio too long", "Bio is too long", http.StatusBadRequest)
+		return
+	}
+
+	userData, err := c.db.GetUserByID(context.Background(), jwt_user_id)
 	if err != nil {
-		respondWithError(writer, fmt.Sprintf("failed to hash the password: %v", err), "Something went wrong", http.StatusInternalServerError)
+		respondWithError(writer, fmt.Sprintf("Failed to get user data: %v", err), "User not found", http.StatusNotFound)
 		return
 	}
+	emailChanged := reqParams.Email != nil && *reqParams.Email != userData.Email
+	passwordChanged := reqParams.Password != nil
 
-	queryParams := database.UpdateUserParams{
-		ID:             jwt_user_id,
-		Email:          reqParams.Email,
-		HashedPassword: hashedPassword,
+	// A stolen access token shouldn't be enough to take over the account, so
+	// changing credentials needs the current password as well.
+	if requirePassword && (emailChanged || passwordChanged) {
+		if len(reqParams.CurrentPassword) <= 0 {
+			respondWithError(writer, "The current password came empty.", "Missing param: current_password", http.StatusBadRequest)
+			return
+		}
+		if !c.reauthenticate(writer, request, userData, reqParams.CurrentPassword) {
+			return
+		}
+	}
+
+	if emailChanged {
+		existingUser, err := c.db.GetUser(context.Background(), *reqParams.Email)
+		if err == nil && existingUser.ID != jwt_user_id {
+			respondWithError(writer, "A user tried to change their email to one already taken.", "Email already taken", http.StatusConflict)
+			return
+		}
+	}
+
+	hashedPassword := ""
+	if passwordChanged {
+		hashedPassword, err = auth.HashPassword(*reqParams.Password)
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("failed to hash the password: %v", err), "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	profileParams := database.UpdateUserProfileParams{ID: jwt_user_id}
+	if reqParams.Username != nil {
+		profileParams.Username = sql.NullString{String: *reqParams.Username, Valid: true}
+	}
+	if reqParams.DisplayName != nil {
+		profileParams.DisplayName = sql.NullString{String: strings.TrimSpace(*reqParams.DisplayName), Valid: true}
+	}
+	if reqParams.Bio != nil {
+		profileParams.Bio = sql.NullString{String: strings.TrimSpace(*reqParams.Bio), Valid: true}
+	}
+	userData, refresh_token, err := c.updateUser(request, profileParams, hashedPassword)
+	if isUniqueViolation(err) {
+		respondWithError(writer, fmt.Sprintf("Failed to save the user to database: %v", err), "Username already taken", http.StatusConflict)
+		return
 	}
-	queryResult, err := c.db.UpdateUser(context.Background(), queryParams)
 	if err != nil {
 		respondWithError(writer, fmt.Sprintf("Failed to save the user to database: %v", err), "Something went wrong", http.StatusInternalServerError)
 		return
 	}
 
-	responseBody := usersResponseParams{
-		ID:          queryResult.ID,
-		CreatedAt:   queryResult.CreatedAt,
-		UpdatedAt:   queryResult.UpdatedAt,
-		Email:       queryResult.Email,
-		IsChirpyRed: queryResult.IsChirpyRed,
+	responseBody := usersPatchResponseParams{usersResponseParams: newUsersResponse(userData)}
+	if passwordChanged {
+		c.logSecurityEvent(jwt_user_id, "password_changed", "The password was changed; every other session and every personal access token was revoked.")
+		responseBody.RefreshToken = refresh_token
+		responseBody.Token, err = auth.MakeJWT(jwt_user_id, c.jwtKeys, c.jwtValidator.Issuer, c.jwtAudience, accessTokenLifetime)
+		if err != nil {
+			respondWithError(writer, fmt.Sprintf("Failed to generate JWT for user: %v", err), "Something went wrong.", http.StatusInternalServerError)
+			return
+		}
+	}
+	if emailChanged {
+		// The change is already saved, and the caller needs the tokens above,
+		// so a lost mail shouldn't fail the request. The link can be resent.
+		err = c.sendEmailVerification(jwt_user_id, *reqParams.Email)
+		if err != nil {
+			fmt.Printf("[Error]: Failed to send the verification email: %v\n", err)
+		}
+		responseBody.PendingEmail = *reqParams.Email
+	}
+	respondWithJSON(writer, responseBody, http.StatusOK)
+}
+
+// reauthenticate checks the password of a user who is already logged in,
+// before a sensitive change. Wrong guesses count towards the same lockout as
+// failed logins. It responds with the error itself and returns false if the
+// request shouldn't go on.
+func (c *apiConfig) reauthenticate(writer http.ResponseWriter, request *http.Request, userData database.User, password string) bool {
+	attempts, retryAfter, err := c.startLoginAttempt(userData.Email, clientIP(request))
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to check login throttling: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return false
+	}
+	if retryAfter > 0 {
+		respondWithTooManyAttempts(writer, retryAfter)
+		return false
+	}
+	correctPassword, err := auth.CheckPasswordHash(password, userData.HashedPassword)
+	if err != nil {
+		c.refundLoginAttempts(attempts)
+		respondWithError(writer, fmt.Sprintf("Failed to compare password and hash: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return false
+	}
+	if !correctPassword {
+		failLoginAttempts(attempts)
+		respondWithError(writer, "Re-authentication with an incorrect password.", "Incorrect password", http.StatusForbidden)
+		return false
+	}
+	c.refundLoginAttempts(attempts)
+	return true
+}
+
+// updateUser saves the profile changes and, when hashedPassword is set, the
+// new password. Access tokens don't say which session they came from, so a
+// password change revokes every refresh token and personal access token and
+// starts a new session for the caller, whose refresh token is returned.
+func (c *apiConfig) updateUser(request *http.Request, profileParams database.UpdateUserProfileParams, hashedPassword string) (database.User, string, error) {
+	tx, err := c.dbConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return database.User{}, "", fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := c.db.WithTx(tx)
+
+	userData, err := qtx.UpdateUserProfile(context.Background(), profileParams)
+	if err != nil {
+		return database.User{}, "", err
+	}
+	if hashedPassword == "" {
+		return userData, "", tx.Commit()
+	}
+
+	userData, err = qtx.UpdateUser(context.Background(), database.UpdateUserParams{
+		ID:             profileParams.ID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return database.User{}, "", fmt.Errorf("failed to update the password: %v", err)
+	}
+	err = qtx.RevokeAllUserRefreshTokens(context.Background(), profileParams.ID)
+	if err != nil {
+		return database.User{}, "", fmt.Errorf("failed to revoke the refresh tokens: %v", err)
+	}
+	err = qtx.RevokeAllUserPersonalAccessTokens(context.Background(), profileParams.ID)
+	if err != nil {
+		return database.User{}, "", fmt.Errorf("failed to revoke the personal access tokens: %v", err)
+	}
+	refresh_token, err := c.issueRefreshToken(qtx, request, profileParams.ID, uuid.New())
+	if err != nil {
+		return database.User{}, "", err
+	}
+	return userData, refresh_token, tx.Commit()
+}
+
+func newUsersResponse(userData database.User) usersResponseParams {
+	return usersResponseParams{
+		ID:            userData.ID,
+		CreatedAt:     userData.CreatedAt,
+		UpdatedAt:     userData.UpdatedAt,
+		Email:         userData.Email,
+		EmailVerified: userData.EmailVerifiedAt.Valid,
+		IsChirpyRed:   userData.IsChirpyRed,
+		Username:      userData.Username.String,
+		DisplayName:   userData.DisplayName,
+		Bio:           userData.Bio,
+	}
+}
+
+func (c *apiConfig) handlerUserProfileGET(writer http.ResponseWriter, request *http.Request) {
+	idOrUsername := request.PathValue("id_or_username")
+
+	var userData database.User
+	userID, err := uuid.Parse(idOrUsername)
+	if err == nil {
+		userData, err = c.db.GetUserByID(context.Background(), userID)
+	} else {
+		userData, err = c.db.GetUserByUsername(context.Background(), idOrUsername)
+	}
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get user data: %v", err), "User not found", http.StatusNotFound)
+		return
+	}
+
+	counts, err := c.db.GetUserProfileCounts(context.Background(), userData.ID)
+	if err != nil {
+		respondWithError(writer, fmt.Sprintf("Failed to get the profile counts: %v", err), "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	responseBody := userProfileResponseParams{
+		ID:             userData.ID,
+		CreatedAt:      userData.CreatedAt,
+		Username:       userData.Username.String,
+		DisplayName:    userData.DisplayName,
+		Bio:            userData.Bio,
+		IsChirpyRed:    userData.IsChirpyRed,
+		ChirpCount:     counts.ChirpCount,
+		FollowerCount:  counts.FollowerCount,
+		FollowingCount: counts.FollowingCount,
 	}
 	respondWithJSON(writer, responseBody, http.StatusOK)
 }